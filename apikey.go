@@ -0,0 +1,123 @@
+package nreventexporter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.uber.org/zap"
+)
+
+// defaultAPIKeyFileReloadInterval is how often api_key_file is re-read when
+// api_key_file_reload_interval is not set.
+const defaultAPIKeyFileReloadInterval = time.Minute
+
+// apiKeyFile holds the API key read from api_key_file and keeps it up to date
+// while the exporter runs, so a rotated key is picked up without a restart.
+// The file is polled rather than watched for events since mounted secrets are
+// usually replaced by swapping a symlink, which file watches don't follow.
+type apiKeyFile struct {
+	path     string
+	keyType  string
+	interval time.Duration
+	logger   *zap.Logger
+
+	key      atomic.Pointer[configopaque.String]
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newAPIKeyFile(path string, keyType string, interval time.Duration, logger *zap.Logger) *apiKeyFile {
+	if interval <= 0 {
+		interval = defaultAPIKeyFileReloadInterval
+	}
+	return &apiKeyFile{
+		path:     path,
+		keyType:  keyType,
+		interval: interval,
+		logger:   logger,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// read returns the key stored in the file, without surrounding whitespace.
+func (f *apiKeyFile) read() (configopaque.String, error) {
+	contents, err := os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("api_key_file %q does not exist", f.path)
+		}
+		return "", fmt.Errorf("failed to read api_key_file %q: %w", f.path, err)
+	}
+	key := configopaque.String(strings.TrimSpace(string(contents)))
+	if key == "" {
+		return "", fmt.Errorf("api_key_file %q is empty", f.path)
+	}
+	if err := validateKeyFormat(f.keyType, key); err != nil {
+		return "", fmt.Errorf("api_key_file %q %w", f.path, err)
+	}
+	return key, nil
+}
+
+// start loads the key and begins reloading it in the background. It fails if
+// the key can't be loaded, so a missing file is reported when the exporter starts.
+func (f *apiKeyFile) start() error {
+	key, err := f.read()
+	if err != nil {
+		return err
+	}
+	f.key.Store(&key)
+
+	go func() {
+		defer close(f.done)
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+				f.reload()
+			}
+		}
+	}()
+	return nil
+}
+
+// reload re-reads the file, keeping the previous key if the new one can't be read.
+func (f *apiKeyFile) reload() {
+	key, err := f.read()
+	if err != nil {
+		f.logger.Error("Failed to reload API key, keeping the previous key", zap.Error(err))
+		return
+	}
+	if previous := f.key.Load(); previous == nil || *previous != key {
+		f.logger.Info("API key reloaded", zap.String("api_key_file", f.path))
+		f.key.Store(&key)
+	}
+}
+
+// get returns the current key, or "" if it hasn't been loaded.
+func (f *apiKeyFile) get() configopaque.String {
+	if key := f.key.Load(); key != nil {
+		return *key
+	}
+	return ""
+}
+
+// shutdown stops the background reload. It is safe to call if start was never called.
+func (f *apiKeyFile) shutdown() {
+	f.stopOnce.Do(func() {
+		close(f.stop)
+		if f.key.Load() != nil {
+			<-f.done
+		}
+	})
+}
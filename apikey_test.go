@@ -0,0 +1,59 @@
+package nreventexporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.uber.org/zap"
+)
+
+// startAPIKeyFile starts reading the key file at path, never reloading on its own.
+func startAPIKeyFile(t *testing.T, path string) (*apiKeyFile, error) {
+	t.Helper()
+	f := newAPIKeyFile(path, keyTypeUser, time.Hour, zap.NewNop())
+	t.Cleanup(f.shutdown)
+	return f, f.start()
+}
+
+func TestAPIKeyFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, []byte("NRAK-first\n"), 0o600))
+	f, err := startAPIKeyFile(t, path)
+	require.NoError(t, err)
+	assert.Equal(t, configopaque.String("NRAK-first"), f.get())
+
+	require.NoError(t, os.WriteFile(path, []byte("NRAK-second"), 0o600))
+	f.reload()
+	assert.Equal(t, configopaque.String("NRAK-second"), f.get())
+
+	// A key that can't be read keeps the previous one in use.
+	require.NoError(t, os.WriteFile(path, []byte("  \n"), 0o600))
+	f.reload()
+	assert.Equal(t, configopaque.String("NRAK-second"), f.get())
+	require.NoError(t, os.Remove(path))
+	f.reload()
+	assert.Equal(t, configopaque.String("NRAK-second"), f.get())
+}
+
+func TestAPIKeyFileStartFails(t *testing.T) {
+	dir := t.TempDir()
+
+	missing := filepath.Join(dir, "missing")
+	_, err := startAPIKeyFile(t, missing)
+	assert.EqualError(t, err, `api_key_file "`+missing+`" does not exist`)
+
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(empty, []byte("\n"), 0o600))
+	_, err = startAPIKeyFile(t, empty)
+	assert.EqualError(t, err, `api_key_file "`+empty+`" is empty`)
+
+	invalid := filepath.Join(dir, "invalid")
+	require.NoError(t, os.WriteFile(invalid, []byte("NRII-insert"), 0o600))
+	_, err = startAPIKeyFile(t, invalid)
+	assert.EqualError(t, err, `api_key_file "`+invalid+`" is not a valid user key: expected the "NRAK-" prefix`)
+}
@@ -0,0 +1,99 @@
+package nreventexporter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+)
+
+const (
+	keyTypeLicense = "license"
+	keyTypeInsert  = "insert"
+	keyTypeUser    = "user"
+
+	headerAPIKey    = "Api-Key"
+	headerInsertKey = "X-Insert-Key"
+
+	licenseKeyLength      = 40
+	insertKeyPrefix       = "NRII-"
+	legacyInsertKeyLength = 32
+	userKeyPrefix         = "NRAK-"
+)
+
+// resolvedKeyType returns the configured key type, defaulting to a license key.
+func (cfg *Config) resolvedKeyType() string {
+	if cfg.KeyType == "" {
+		return keyTypeLicense
+	}
+	return strings.ToLower(cfg.KeyType)
+}
+
+// apiKeyHeader returns the header the API key is sent in. License and user keys
+// go in Api-Key, which every ingest API accepts; legacy Insights insert keys
+// must be sent in X-Insert-Key.
+func (cfg *Config) apiKeyHeader() string {
+	if cfg.resolvedKeyType() == keyTypeInsert {
+		return headerInsertKey
+	}
+	return headerAPIKey
+}
+
+// validateAPIKey checks the key type and that a configured key has the format
+// New Relic issues for that type.
+func (cfg *Config) validateAPIKey() error {
+	keyType := cfg.resolvedKeyType()
+	switch keyType {
+	case keyTypeLicense, keyTypeInsert, keyTypeUser:
+	default:
+		return fmt.Errorf("key_type must be one of %q, %q or %q, got %q", keyTypeLicense, keyTypeInsert, keyTypeUser, cfg.KeyType)
+	}
+	if cfg.APIKey == "" {
+		return nil
+	}
+	if err := validateKeyFormat(keyType, cfg.APIKey); err != nil {
+		return fmt.Errorf("api_key %w", err)
+	}
+	return nil
+}
+
+// validateKeyFormat checks that key has the format New Relic issues for keyType.
+func validateKeyFormat(keyType string, key configopaque.String) error {
+	switch keyType {
+	case keyTypeLicense:
+		if len(key) != licenseKeyLength {
+			return fmt.Errorf("is not a valid license key: expected %d characters, got %d", licenseKeyLength, len(key))
+		}
+	case keyTypeInsert:
+		if !strings.HasPrefix(string(key), insertKeyPrefix) && len(key) != legacyInsertKeyLength {
+			return fmt.Errorf("is not a valid insert key: expected the %q prefix or %d characters", insertKeyPrefix, legacyInsertKeyLength)
+		}
+	case keyTypeUser:
+		if !strings.HasPrefix(string(key), userKeyPrefix) {
+			return fmt.Errorf("is not a valid user key: expected the %q prefix", userKeyPrefix)
+		}
+	}
+	return nil
+}
+
+// redactedHeaders returns a copy of the request headers that is safe to log, with
+// the value of every header that can carry an API key replaced.
+func redactedHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	for _, name := range []string{headerAPIKey, headerInsertKey, "X-License-Key", "Authorization"} {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "[REDACTED]")
+		}
+	}
+	return redacted
+}
+
+// redactAPIKey replaces any occurrence of apiKey in msg, so text echoed back by
+// a server or proxy can be put into errors and logs.
+func redactAPIKey(msg string, apiKey configopaque.String) string {
+	if apiKey == "" {
+		return msg
+	}
+	return strings.ReplaceAll(msg, string(apiKey), "[REDACTED]")
+}
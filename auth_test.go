@@ -0,0 +1,93 @@
+package nreventexporter
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/config/configopaque"
+)
+
+func TestRedactedHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set(headerAPIKey, "NRAK-secret")
+	headers.Set(headerInsertKey, "NRII-secret")
+	headers.Set("X-License-Key", "secret")
+	headers.Set("Authorization", "Bearer secret")
+	headers.Set("Content-Type", "application/json")
+
+	redacted := redactedHeaders(headers)
+	for _, name := range []string{headerAPIKey, headerInsertKey, "X-License-Key", "Authorization"} {
+		assert.Equal(t, "[REDACTED]", redacted.Get(name), name)
+	}
+	assert.Equal(t, "application/json", redacted.Get("Content-Type"))
+	assert.Equal(t, "NRAK-secret", headers.Get(headerAPIKey), "the request headers are left alone")
+}
+
+func TestRedactAPIKey(t *testing.T) {
+	assert.Equal(t, "invalid key [REDACTED] for [REDACTED]", redactAPIKey("invalid key NRAK-secret for NRAK-secret", "NRAK-secret"))
+	assert.Equal(t, "no key here", redactAPIKey("no key here", "NRAK-secret"))
+	assert.Equal(t, "no key configured", redactAPIKey("no key configured", ""))
+}
+
+func TestRejectedSuccessResponseRedactsAPIKey(t *testing.T) {
+	e := newTestExporter(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"success":false,"error":"key NRAK-test is not allowed"}`))
+	}, nil)
+
+	err := e.pushLogs(context.Background(), testLogs(1))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "key [REDACTED] is not allowed")
+	assert.NotContains(t, err.Error(), "NRAK-test")
+}
+
+func TestAPIKeyHeader(t *testing.T) {
+	tests := []struct {
+		keyType string
+		want    string
+	}{
+		{keyType: "", want: headerAPIKey},
+		{keyType: keyTypeLicense, want: headerAPIKey},
+		{keyType: keyTypeUser, want: headerAPIKey},
+		{keyType: keyTypeInsert, want: headerInsertKey},
+		{keyType: "Insert", want: headerInsertKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keyType, func(t *testing.T) {
+			cfg := &Config{KeyType: tt.keyType}
+			assert.Equal(t, tt.want, cfg.apiKeyHeader())
+		})
+	}
+}
+
+func TestValidateKeyFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyType string
+		key     string
+		err     string
+	}{
+		{name: "license", keyType: keyTypeLicense, key: strings.Repeat("a", licenseKeyLength)},
+		{name: "EU license", keyType: keyTypeLicense, key: "eu01xx" + strings.Repeat("a", licenseKeyLength-10) + "NRAL"},
+		{name: "short license", keyType: keyTypeLicense, key: "abc", err: "is not a valid license key: expected 40 characters, got 3"},
+		{name: "insert", keyType: keyTypeInsert, key: "NRII-abc"},
+		{name: "legacy insert", keyType: keyTypeInsert, key: strings.Repeat("a", legacyInsertKeyLength)},
+		{name: "bad insert", keyType: keyTypeInsert, key: "abc", err: `is not a valid insert key: expected the "NRII-" prefix or 32 characters`},
+		{name: "user", keyType: keyTypeUser, key: "NRAK-abc"},
+		{name: "bad user", keyType: keyTypeUser, key: "NRII-abc", err: `is not a valid user key: expected the "NRAK-" prefix`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateKeyFormat(tt.keyType, configopaque.String(tt.key))
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -3,26 +3,311 @@
 
 package nreventexporter // import "github.com/shelson/nreventexporter"
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jwang25/nreventexporter/internal/compression"
+	"github.com/jwang25/nreventexporter/internal/metricfilter"
 	"go.opentelemetry.io/collector/component"
-	"go.opentelemetry.io/collector/exporter/otlphttpexporter"
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
 )
 
-// Config defines configuration for OTLP/HTTP exporter.
+// Config defines configuration for the New Relic event exporter.
 type Config struct {
-	OtlpHttpExporterConfig *otlphttpexporter.Config `mapstructure:",squash"` //brings the configs to the top level
-	//confighttp.ClientConfig    `mapstructure:",squash"`  // squash ensures fields are correctly decoded in embedded struct.
-	//RetryConfig                configretry.BackOffConfig `mapstructure:"retry_on_failure"`
-	//exporterhelper.QueueConfig `mapstructure:"sending_queue"`
-	eventType string `mapstructure:"eventType"`
-	// The URL to send metrics to. If omitted the Endpoint + "/v1/metrics" will be used.
-	//MetricsEndpoint string `mapstructure:"metrics_endpoint"`
-	// API key to use when sending data to the New Relic backend.
-	APIKey string `mapstructure:"api_key"`
+	confighttp.ClientConfig    `mapstructure:",squash"` // squash ensures fields are correctly decoded in embedded struct.
+	exporterhelper.QueueConfig `mapstructure:"sending_queue"`
+	RetryConfig                configretry.BackOffConfig `mapstructure:"retry_on_failure"`
+	// EventType is the eventType of the events metrics, logs and spans are converted to.
+	// An eventType attribute on a log record or span overrides it.
+	EventType string `mapstructure:"event_type"`
+	// The URL to send metrics to. If omitted events are sent to endpoint and the
+	// Metric API URL is derived from region.
+	MetricsEndpoint string `mapstructure:"metrics_endpoint"`
+	// The URL to send logs to. If omitted events are sent to endpoint and the
+	// Log API URL is derived from region.
+	LogsEndpoint string `mapstructure:"logs_endpoint"`
+	// The URL to send spans to. If omitted events are sent to endpoint and the
+	// Trace API URL is derived from region.
+	TracesEndpoint string `mapstructure:"traces_endpoint"`
+	// API key to use when sending data to the New Relic backend. It is a secret and
+	// never appears in logs, errors or config dumps.
+	APIKey configopaque.String `mapstructure:"api_key"`
+	// APIKeyFile is a file holding the API key, used instead of api_key. The file is
+	// re-read every APIKeyFileReloadInterval (default 1m) so rotated keys are picked up
+	// without a restart. Keys in environment variables can be set with api_key: ${env:VAR}.
+	APIKeyFile string `mapstructure:"api_key_file"`
+	// APIKeyFileReloadInterval is how often api_key_file is re-read.
+	APIKeyFileReloadInterval time.Duration `mapstructure:"api_key_file_reload_interval"`
+	// KeyType is the kind of key in APIKey: "license" (default), "insert" for legacy
+	// Insights insert keys, or "user". It selects the header the key is sent in.
+	KeyType string `mapstructure:"key_type"`
+	// MetricFilter selects which metrics are converted to events. Metrics are
+	// filtered by name before conversion so other exporters in the pipeline are unaffected.
+	MetricFilter MetricFilterConfig `mapstructure:"metric_filter"`
+	// NormalizeUnits converts metric values to seconds, bytes or ratios based on
+	// the metric's unit and records the resulting unit on each event.
+	NormalizeUnits bool `mapstructure:"normalize_units"`
+	// SpanEvents emits every span event (exceptions, retries, ...) as its own
+	// event alongside the span, carrying the span's trace.id and span.id.
+	SpanEvents bool `mapstructure:"span_events"`
+	// SpanLinks emits every span link as its own event alongside the span.
+	SpanLinks bool `mapstructure:"span_links"`
+	// LogsFormat selects how log records are sent: "event" (default) converts them to
+	// custom events, "log" sends them to the New Relic Log API.
+	LogsFormat string `mapstructure:"logs_format"`
+	// MetricsFormat selects how metrics are sent: "event" (default) converts them to
+	// custom events, "metric" sends them to the New Relic Metric API as dimensional metrics.
+	MetricsFormat string `mapstructure:"metrics_format"`
+	// TracesFormat selects how spans are sent: "event" (default) converts them to
+	// custom events, "trace" sends them to the New Relic Trace API.
+	TracesFormat string `mapstructure:"traces_format"`
+	// Region is the New Relic datacenter to send to: "US", "EU", "FedRAMP" or "staging".
+	// When omitted it is detected from the license key, defaulting to US.
+	Region string `mapstructure:"region"`
+	// AccountID is used with Region to build the Event API URL when endpoint is not set.
+	AccountID string `mapstructure:"account_id"`
+	// RetryableStatusCodes lists the HTTP status codes that are retried. When empty
+	// 429, 502, 503 and 504 are retried. 413 is not allowed, as payloads rejected as
+	// too large are split instead of retried whole.
+	RetryableStatusCodes []int `mapstructure:"retryable_status_codes"`
+	// MaxRetryAfter caps the delay honored from a Retry-After response header.
+	// Zero means the server's delay is always honored.
+	MaxRetryAfter time.Duration `mapstructure:"max_retry_after"`
+	// Accounts maps values of the routing attribute to the New Relic account metrics
+	// carrying that value are sent to, each with its own API key.
+	Accounts map[string]AccountConfig `mapstructure:"accounts"`
+	// RoutingAttribute is the resource attribute matched against the keys of Accounts.
+	// Defaults to "nr.account_id".
+	RoutingAttribute string `mapstructure:"routing_attribute"`
+	// DefaultAccount names the account metrics without a matching routing attribute
+	// are sent to. When empty they are sent with api_key to the configured endpoint.
+	DefaultAccount string `mapstructure:"default_account"`
+	// APIKeyMetadataKey names the client metadata entry holding the API key to send
+	// metrics with, falling back to api_key. Requires include_metadata on the receiver.
+	APIKeyMetadataKey string `mapstructure:"api_key_metadata_key"`
+	// AccountIDMetadataKey names the client metadata entry holding the account ID
+	// used to build the Event API URL for the request.
+	AccountIDMetadataKey string `mapstructure:"account_id_metadata_key"`
+	// ChunkSize is the maximum number of events sent in one request. Larger batches
+	// are split into chunks sent concurrently. Zero sends each batch in one request.
+	ChunkSize int `mapstructure:"chunk_size"`
+	// MaxConcurrentChunks is the maximum number of chunk requests in flight at once.
+	// Defaults to 4.
+	MaxConcurrentChunks int `mapstructure:"max_concurrent_chunks"`
+	// CircuitBreaker stops sending to a failing endpoint for a while.
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+	// EventIDs adds an otel.event.id attribute to every event, a hash of the event's
+	// attributes and timestamp, so events duplicated by retries can be removed in NRQL.
+	EventIDs bool `mapstructure:"event_ids"`
+	// StartupCheck posts an empty batch when the exporter starts, so a rejected API
+	// key fails the start instead of the first export. Unreachable or unavailable
+	// endpoints are reported as a recoverable component status.
+	StartupCheck bool `mapstructure:"startup_check"`
+	// RateLimit caps how fast requests are sent.
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	// Failover lists endpoints tried when the primary endpoint is unreachable.
+	Failover FailoverConfig `mapstructure:"failover"`
+}
+
+// CircuitBreakerConfig configures the circuit breaker around requests. After
+// FailureThreshold consecutive failures (5xx responses, timeouts, connection errors)
+// requests fail fast with a retryable error for CoolDown, after which a single probe
+// request decides whether sending resumes.
+type CircuitBreakerConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// FailureThreshold defaults to 5.
+	FailureThreshold int `mapstructure:"failure_threshold"`
+	// CoolDown defaults to 30s.
+	CoolDown time.Duration `mapstructure:"cool_down"`
+}
+
+// RateLimitConfig configures client-side token-bucket rate limits, applied to every
+// request after compression. A zero limit is unlimited.
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"`
+	EventsPerMinute   int     `mapstructure:"events_per_minute"`
+	BytesPerMinute    int     `mapstructure:"bytes_per_minute"`
+	// Mode is "block" (default) to wait until the request is allowed, or "throttle"
+	// to fail it with a retryable error so the retry and queue settings apply.
+	Mode string `mapstructure:"mode"`
+}
+
+const (
+	// rateLimitBlock waits for the rate limiter before sending.
+	rateLimitBlock = "block"
+	// rateLimitThrottle fails rate limited requests with a throttle-retry error.
+	rateLimitThrottle = "throttle"
+)
+
+const (
+	// formatEvent sends data as custom events to the Event API.
+	formatEvent = "event"
+	// formatLog sends logs to the Log API.
+	formatLog = "log"
+	// formatMetric sends metrics to the Metric API.
+	formatMetric = "metric"
+	// formatTrace sends spans to the Trace API.
+	formatTrace = "trace"
+)
+
+// MetricFilterConfig holds the include and exclude rules applied to metric names.
+// When include is set only matching metrics are kept; exclude is applied afterwards.
+type MetricFilterConfig struct {
+	Include *MetricMatchConfig `mapstructure:"include"`
+	Exclude *MetricMatchConfig `mapstructure:"exclude"`
+}
+
+// MetricMatchConfig lists metric name patterns and how to interpret them.
+type MetricMatchConfig struct {
+	// MatchType is one of "strict" (default), "glob" or "regexp".
+	MatchType string `mapstructure:"match_type"`
+	// MetricNames are the patterns metric names are matched against.
+	MetricNames []string `mapstructure:"metric_names"`
 }
 
 var _ component.Config = (*Config)(nil)
 
 // Validate checks if the exporter configuration is valid
 func (cfg *Config) Validate() error {
-	return (*cfg).OtlpHttpExporterConfig.Validate()
+	if err := validateEventType(cfg.EventType); err != nil {
+		return err
+	}
+	if _, err := cfg.MetricFilter.buildFilter(); err != nil {
+		return err
+	}
+	switch cfg.LogsFormat {
+	case "", formatEvent, formatLog:
+	default:
+		return fmt.Errorf("logs_format must be one of %q or %q, got %q", formatEvent, formatLog, cfg.LogsFormat)
+	}
+	switch cfg.MetricsFormat {
+	case "", formatEvent, formatMetric:
+	default:
+		return fmt.Errorf("metrics_format must be one of %q or %q, got %q", formatEvent, formatMetric, cfg.MetricsFormat)
+	}
+	switch cfg.TracesFormat {
+	case "", formatEvent, formatTrace:
+	default:
+		return fmt.Errorf("traces_format must be one of %q or %q, got %q", formatEvent, formatTrace, cfg.TracesFormat)
+	}
+	for _, code := range cfg.RetryableStatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("retryable_status_codes: %d is not a valid HTTP status code", code)
+		}
+		if code == http.StatusRequestEntityTooLarge {
+			return errors.New("retryable_status_codes: 413 can't be retried, payloads rejected as too large are split and resent instead")
+		}
+	}
+	if cfg.MaxRetryAfter < 0 {
+		return errors.New("max_retry_after must not be negative")
+	}
+	// The compression and compression_params options of the HTTP client select
+	// how payloads are encoded, limited to what the New Relic APIs accept.
+	if err := compression.Validate(string(cfg.Compression), int(cfg.CompressionParams.Level)); err != nil {
+		return err
+	}
+	if cfg.APIKey != "" && cfg.APIKeyFile != "" {
+		return errors.New("only one of api_key and api_key_file may be set")
+	}
+	if cfg.APIKey == "" && cfg.APIKeyFile == "" && cfg.APIKeyMetadataKey == "" && cfg.DefaultAccount == "" {
+		return errors.New("api_key or api_key_file must be set")
+	}
+	if cfg.APIKeyFileReloadInterval < 0 {
+		return errors.New("api_key_file_reload_interval must not be negative")
+	}
+	if cfg.ChunkSize < 0 {
+		return errors.New("chunk_size must not be negative")
+	}
+	if cfg.MaxConcurrentChunks < 0 {
+		return errors.New("max_concurrent_chunks must not be negative")
+	}
+	if cfg.CircuitBreaker.FailureThreshold < 0 {
+		return errors.New("circuit_breaker::failure_threshold must not be negative")
+	}
+	if cfg.CircuitBreaker.CoolDown < 0 {
+		return errors.New("circuit_breaker::cool_down must not be negative")
+	}
+	if cfg.RateLimit.RequestsPerSecond < 0 || cfg.RateLimit.EventsPerMinute < 0 || cfg.RateLimit.BytesPerMinute < 0 {
+		return errors.New("rate_limit limits must not be negative")
+	}
+	switch cfg.RateLimit.Mode {
+	case "", rateLimitBlock, rateLimitThrottle:
+	default:
+		return fmt.Errorf("rate_limit::mode must be one of %q or %q, got %q", rateLimitBlock, rateLimitThrottle, cfg.RateLimit.Mode)
+	}
+	if err := cfg.validateFailover(); err != nil {
+		return err
+	}
+	if err := cfg.validateAccounts(); err != nil {
+		return err
+	}
+	if err := cfg.validateAPIKey(); err != nil {
+		return err
+	}
+	if err := cfg.validateRegion(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateEventsDestination checks that a signal sent as events has an Event API URL
+// to go to. It runs when the signal's exporter is created, as only then is it known
+// which signals the exporter is used for.
+func (cfg *Config) validateEventsDestination(signal, format, signalEndpoint string) error {
+	if format != "" && format != formatEvent {
+		return nil
+	}
+	if cfg.eventsEndpoint(signalEndpoint, cfg.resolvedRegion()) != "" {
+		return nil
+	}
+	if account, ok := cfg.Accounts[cfg.DefaultAccount]; signal == "metrics" && ok && (account.AccountID != "" || account.Endpoint != "") {
+		// Every metric is routed to an account with its own destination.
+		return nil
+	}
+	return fmt.Errorf("%s are sent as events, which requires one of %s_endpoint, endpoint or account_id to be set", signal, signal)
+}
+
+// maxEventTypeLength is the longest eventType the Event API accepts.
+const maxEventTypeLength = 255
+
+// validateEventType checks eventType against the Event API's naming rules: letters,
+// digits, underscores and colons, at most 255 characters.
+func validateEventType(eventType string) error {
+	if eventType == "" {
+		return errors.New("event_type must be set")
+	}
+	if len(eventType) > maxEventTypeLength {
+		return fmt.Errorf("event_type must be at most %d characters, got %d", maxEventTypeLength, len(eventType))
+	}
+	for _, r := range eventType {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == ':') {
+			return fmt.Errorf("event_type %q may only contain letters, digits, underscores and colons", eventType)
+		}
+	}
+	return nil
+}
+
+func (mc *MetricMatchConfig) buildMatcher() (metricfilter.Matcher, error) {
+	if mc == nil {
+		return nil, nil
+	}
+	return metricfilter.NewMatcher(metricfilter.MatchType(mc.MatchType), mc.MetricNames)
+}
+
+// buildFilter compiles the configured patterns into a metricfilter.Filter.
+func (fc *MetricFilterConfig) buildFilter() (*metricfilter.Filter, error) {
+	include, err := fc.Include.buildMatcher()
+	if err != nil {
+		return nil, fmt.Errorf("metric_filter::include: %w", err)
+	}
+	exclude, err := fc.Exclude.buildMatcher()
+	if err != nil {
+		return nil, fmt.Errorf("metric_filter::exclude: %w", err)
+	}
+	return metricfilter.NewFilter(include, exclude), nil
 }
@@ -0,0 +1,127 @@
+package nreventexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+)
+
+// loadConfig unmarshals settings over the default config.
+func loadConfig(t *testing.T, settings map[string]any) *Config {
+	t.Helper()
+	cfg := createDefaultConfig().(*Config)
+	require.NoError(t, confmap.NewFromStringMap(settings).Unmarshal(cfg))
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]any
+		err      string
+	}{
+		{
+			name:     "account",
+			settings: map[string]any{"api_key": "NRAK-test", "key_type": "user", "account_id": "1"},
+		},
+		{
+			name:     "metrics endpoint only",
+			settings: map[string]any{"api_key": "NRAK-test", "key_type": "user", "metrics_endpoint": "https://relay.example.com/v1/accounts/1/events"},
+		},
+		{
+			name:     "metric api in region",
+			settings: map[string]any{"api_key": "NRAK-test", "key_type": "user", "metrics_format": "metric", "region": "EU"},
+		},
+		{
+			name: "unknown account region",
+			settings: map[string]any{"api_key": "NRAK-test", "key_type": "user", "account_id": "1",
+				"accounts": map[string]any{"a": map[string]any{"api_key": "NRAK-a", "account_id": "2", "region": "Mars"}}},
+			err: `accounts::a: region must be one of "US", "EU", "FedRAMP" or "staging", got "Mars"`,
+		},
+		{
+			name: "account endpoint in another region",
+			settings: map[string]any{"api_key": "NRAK-test", "key_type": "user", "account_id": "1",
+				"accounts": map[string]any{"a": map[string]any{"api_key": "NRAK-a", "region": "EU",
+					"endpoint": "https://insights-collector.newrelic.com/v1/accounts/2/events"}}},
+			err: `accounts::a: endpoint "https://insights-collector.newrelic.com/v1/accounts/2/events" belongs to region US but the account's region is EU`,
+		},
+		{
+			name:     "EU endpoint without region",
+			settings: map[string]any{"api_key": "NRII-test", "key_type": "insert", "metrics_format": "metric", "metrics_endpoint": "https://metric-api.eu.newrelic.com/metric/v1"},
+		},
+		{
+			name:     "EU endpoint with api_key_file",
+			settings: map[string]any{"api_key_file": "/etc/newrelic/key", "metrics_format": "metric", "metrics_endpoint": "https://metric-api.eu.newrelic.com/metric/v1"},
+		},
+		{
+			name:     "EU endpoint in US region",
+			settings: map[string]any{"api_key": "NRII-test", "key_type": "insert", "region": "US", "metrics_format": "metric", "metrics_endpoint": "https://metric-api.eu.newrelic.com/metric/v1"},
+			err:      `metrics_endpoint "https://metric-api.eu.newrelic.com/metric/v1" belongs to region EU but region is US`,
+		},
+		{
+			name:     "413 retryable",
+			settings: map[string]any{"api_key": "NRAK-test", "key_type": "user", "account_id": "1", "retryable_status_codes": []any{429, 413}},
+			err:      "retryable_status_codes: 413 can't be retried, payloads rejected as too large are split and resent instead",
+		},
+		{
+			name:     "missing api key",
+			settings: map[string]any{"account_id": "1"},
+			err:      "api_key or api_key_file must be set",
+		},
+		{
+			name:     "invalid event type",
+			settings: map[string]any{"api_key": "NRAK-test", "key_type": "user", "account_id": "1", "event_type": "Otel Event"},
+			err:      `event_type "Otel Event" may only contain letters, digits, underscores and colons`,
+		},
+		{
+			name:     "empty event type",
+			settings: map[string]any{"api_key": "NRAK-test", "key_type": "user", "account_id": "1", "event_type": ""},
+			err:      "event_type must be set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadConfig(t, tt.settings).Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestEventsEndpointFallsBackToMetricsEndpoint(t *testing.T) {
+	const endpoint = "https://relay.example.com/v1/accounts/1/events"
+	cfg := loadConfig(t, map[string]any{"api_key": "NRAK-test", "key_type": "user", "metrics_endpoint": endpoint})
+	require.NoError(t, cfg.Validate())
+
+	assert.Equal(t, endpoint, cfg.eventsEndpoint(cfg.LogsEndpoint, regionUS))
+	assert.Equal(t, endpoint, cfg.eventsEndpoint(cfg.TracesEndpoint, regionUS))
+
+	cfg.MetricsFormat = formatMetric
+	assert.Empty(t, cfg.eventsEndpoint(cfg.LogsEndpoint, regionUS), "a Metric API URL can't receive events")
+}
+
+func TestCreateRequiresEventsDestinationPerSignal(t *testing.T) {
+	factory := NewFactory()
+	set := exportertest.NewNopSettings()
+	cfg := loadConfig(t, map[string]any{"api_key": "NRAK-test", "key_type": "user", "metrics_format": "metric", "region": "EU"})
+	require.NoError(t, cfg.Validate())
+
+	metrics, err := factory.CreateMetrics(context.Background(), set, cfg)
+	require.NoError(t, err)
+	require.NoError(t, metrics.Shutdown(context.Background()))
+
+	_, err = factory.CreateLogs(context.Background(), set, cfg)
+	assert.EqualError(t, err, "logs are sent as events, which requires one of logs_endpoint, endpoint or account_id to be set")
+
+	cfg.LogsFormat = formatLog
+	logs, err := factory.CreateLogs(context.Background(), set, cfg)
+	require.NoError(t, err)
+	require.NoError(t, logs.Shutdown(context.Background()))
+}
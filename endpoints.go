@@ -0,0 +1,205 @@
+package nreventexporter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+)
+
+const (
+	regionUS      = "US"
+	regionEU      = "EU"
+	regionFedRAMP = "FedRAMP"
+	regionStaging = "staging"
+
+	// euLicenseKeyPrefix marks license keys issued for accounts in the EU datacenter.
+	euLicenseKeyPrefix = "eu01"
+)
+
+// regionHosts lists the ingest API hosts for a New Relic datacenter.
+type regionHosts struct {
+	events  string
+	logs    string
+	metrics string
+	traces  string
+}
+
+var regions = map[string]regionHosts{
+	regionUS: {
+		events:  "insights-collector.newrelic.com",
+		logs:    "log-api.newrelic.com",
+		metrics: "metric-api.newrelic.com",
+		traces:  "trace-api.newrelic.com",
+	},
+	regionEU: {
+		events:  "insights-collector.eu01.nr-data.net",
+		logs:    "log-api.eu.newrelic.com",
+		metrics: "metric-api.eu.newrelic.com",
+		traces:  "trace-api.eu.newrelic.com",
+	},
+	regionFedRAMP: {
+		events:  "gov-insights-collector.newrelic.com",
+		logs:    "gov-log-api.newrelic.com",
+		metrics: "gov-metric-api.newrelic.com",
+		traces:  "gov-trace-api.newrelic.com",
+	},
+	regionStaging: {
+		events:  "staging-insights-collector.newrelic.com",
+		logs:    "staging-log-api.newrelic.com",
+		metrics: "staging-metric-api.newrelic.com",
+		traces:  "staging-trace-api.newrelic.com",
+	},
+}
+
+// lookupRegion returns the canonical name of a region, matching case-insensitively.
+func lookupRegion(name string) (string, bool) {
+	for region := range regions {
+		if strings.EqualFold(region, name) {
+			return region, true
+		}
+	}
+	return "", false
+}
+
+// resolvedRegion returns the region of the configured api_key.
+func (cfg *Config) resolvedRegion() string {
+	return cfg.regionForKey(cfg.APIKey)
+}
+
+// regionForKey returns the region requests sent with key go to: EU for license keys
+// with the eu01 prefix, which can only be used there, else the configured region,
+// else US.
+func (cfg *Config) regionForKey(key configopaque.String) string {
+	if cfg.isEULicenseKey(key) {
+		return regionEU
+	}
+	if region, ok := lookupRegion(cfg.Region); ok {
+		return region
+	}
+	return regionUS
+}
+
+// accountRegion returns the region of a routed account: its own region if set,
+// else the region of its key.
+func (cfg *Config) accountRegion(account AccountConfig) string {
+	if region, ok := lookupRegion(account.Region); ok {
+		return region
+	}
+	return cfg.regionForKey(account.APIKey)
+}
+
+// hasKnownRegion reports whether the region requests sent with key go to was chosen,
+// by setting region or by using an EU license key, rather than defaulted to US.
+func (cfg *Config) hasKnownRegion(key configopaque.String) bool {
+	return cfg.Region != "" || cfg.isEULicenseKey(key)
+}
+
+// hasEULicenseKey reports whether the API key is a license key for the EU datacenter.
+func (cfg *Config) hasEULicenseKey() bool {
+	return cfg.isEULicenseKey(cfg.APIKey)
+}
+
+// isEULicenseKey reports whether key is a license key for the EU datacenter.
+func (cfg *Config) isEULicenseKey(key configopaque.String) bool {
+	return cfg.resolvedKeyType() == keyTypeLicense && strings.HasPrefix(string(key), euLicenseKeyPrefix)
+}
+
+// eventsEndpoint returns the URL events for a signal are sent to: the signal specific
+// endpoint if set, else endpoint, else metrics_endpoint when metrics are sent as
+// events, else the URL built from account_id and region, or "" without an account_id.
+func (cfg *Config) eventsEndpoint(signalEndpoint, region string) string {
+	if signalEndpoint != "" {
+		return signalEndpoint
+	}
+	if cfg.Endpoint != "" {
+		return cfg.Endpoint
+	}
+	if cfg.MetricsEndpoint != "" && cfg.MetricsFormat != formatMetric {
+		return cfg.MetricsEndpoint
+	}
+	if cfg.AccountID == "" {
+		return ""
+	}
+	return eventAPIEndpoint(region, cfg.AccountID)
+}
+
+// eventAPIEndpoint returns the Event API URL for accountID in region.
+func eventAPIEndpoint(region, accountID string) string {
+	return fmt.Sprintf("https://%s/v1/accounts/%s/events", regions[region].events, accountID)
+}
+
+func logAPIEndpoint(region string) string {
+	return fmt.Sprintf("https://%s/log/v1", regions[region].logs)
+}
+
+func metricAPIEndpoint(region string) string {
+	return fmt.Sprintf("https://%s/metric/v1", regions[region].metrics)
+}
+
+func traceAPIEndpoint(region string) string {
+	return fmt.Sprintf("https://%s/trace/v1", regions[region].traces)
+}
+
+// validateRegion checks the region and account options and that explicitly
+// configured endpoints don't point at another region's New Relic hosts.
+func (cfg *Config) validateRegion() error {
+	if cfg.Region != "" {
+		if _, ok := lookupRegion(cfg.Region); !ok {
+			return fmt.Errorf("region must be one of %q, %q, %q or %q, got %q",
+				regionUS, regionEU, regionFedRAMP, regionStaging, cfg.Region)
+		}
+	}
+	if cfg.AccountID != "" && !isNumeric(cfg.AccountID) {
+		return fmt.Errorf("account_id must be numeric, got %q", cfg.AccountID)
+	}
+
+	if region, _ := lookupRegion(cfg.Region); cfg.Region != "" && region != regionEU && cfg.hasEULicenseKey() {
+		return fmt.Errorf("region %q contradicts the EU license key", region)
+	}
+	region := cfg.resolvedRegion()
+
+	endpoints := []struct{ name, url string }{
+		{"endpoint", cfg.Endpoint},
+		{"metrics_endpoint", cfg.MetricsEndpoint},
+		{"logs_endpoint", cfg.LogsEndpoint},
+		{"traces_endpoint", cfg.TracesEndpoint},
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.url == "" {
+			continue
+		}
+		u, err := url.Parse(endpoint.url)
+		if err != nil {
+			return fmt.Errorf("%s must be a valid URL: %w", endpoint.name, err)
+		}
+		// Without a region or an EU license key the US default is only a guess, so an
+		// endpoint in another region is taken as the intended one.
+		if other, ok := regionOfHost(u.Hostname()); ok && cfg.hasKnownRegion(cfg.APIKey) && other != region {
+			return fmt.Errorf("%s %q belongs to region %s but region is %s", endpoint.name, endpoint.url, other, region)
+		}
+	}
+	return nil
+}
+
+// regionOfHost reports which region a New Relic ingest host belongs to. Hosts that
+// aren't New Relic's (proxies, relays) don't belong to any region.
+func regionOfHost(host string) (string, bool) {
+	for region, hosts := range regions {
+		switch host {
+		case hosts.events, hosts.logs, hosts.metrics, hosts.traces:
+			return region, true
+		}
+	}
+	return "", false
+}
+
+func isNumeric(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
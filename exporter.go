@@ -1,143 +1,427 @@
 package nreventexporter
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jwang25/nreventexporter/internal/circuitbreaker"
+	"github.com/jwang25/nreventexporter/internal/compression"
+	"github.com/jwang25/nreventexporter/internal/failover"
 	"github.com/jwang25/nreventexporter/internal/httphelper"
+	"github.com/jwang25/nreventexporter/internal/idempotency"
+	"github.com/jwang25/nreventexporter/internal/logtoevent"
+	"github.com/jwang25/nreventexporter/internal/logtonrlog"
 	"github.com/jwang25/nreventexporter/internal/metadata"
+	"github.com/jwang25/nreventexporter/internal/metricfilter"
 	"github.com/jwang25/nreventexporter/internal/metrictoevent"
+	"github.com/jwang25/nreventexporter/internal/metrictonrmetric"
+	"github.com/jwang25/nreventexporter/internal/ratelimit"
+	"github.com/jwang25/nreventexporter/internal/spantoevent"
+	"github.com/jwang25/nreventexporter/internal/spantonrtrace"
 	"go.opentelemetry.io/collector/component"
-	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/component/componentstatus"
+	"go.opentelemetry.io/collector/config/configopaque"
 	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
+	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
-	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
-	"google.golang.org/genproto/googleapis/rpc/status"
-	"google.golang.org/protobuf/proto"
 )
 
-var _ exporter.Metrics = (*baseExporter)(nil)
-
 type baseExporter struct {
 	// Input configuration.
-	config       *Config
-	client       *http.Client
-	logger       *zap.Logger
-	settings     exporter.Settings
-	otlpExporter *exporter.Metrics
+	signal   pipeline.Signal
+	config   *Config
+	client   *http.Client
+	logger   *zap.Logger
+	settings exporter.Settings
 	// Default user-agent header.
 	userAgent        string
 	telemetryBuilder *metadata.TelemetryBuilder
+	metricFilter     *metricfilter.Filter
+	apiKeyFile       *apiKeyFile
+	breaker          *circuitbreaker.Breaker
+	limiter          *ratelimit.Limiter
+	failover         *failover.Selector
 }
 
 const (
 	headerRetryAfter         = "Retry-After"
+	headerRequestID          = "X-Request-ID"
 	maxHTTPResponseReadBytes = 64 * 1024
+	maxErrorMessageLength    = 512
+
+	jsonContentType = "application/json"
 
-	jsonContentType     = "application/json"
-	protobufContentType = "application/x-protobuf"
+	defaultCircuitBreakerFailureThreshold = 5
+	defaultCircuitBreakerCoolDown         = 30 * time.Second
 )
 
+// traceAPIHeaders select the newrelic data format on the Trace API.
+var traceAPIHeaders = map[string]string{
+	"Data-Format":         "newrelic",
+	"Data-Format-Version": "1",
+}
+
 // Create new exporter.
-func newExporter(otlpExporter *exporter.Metrics, cfg Config, set exporter.Settings, telemetryBuilder *metadata.TelemetryBuilder) (*baseExporter, error) {
-	if cfg.OtlpHttpExporterConfig.MetricsEndpoint != "" {
-		_, err := url.Parse(cfg.OtlpHttpExporterConfig.MetricsEndpoint)
+func newExporter(signal pipeline.Signal, cfg Config, set exporter.Settings, telemetryBuilder *metadata.TelemetryBuilder) (*baseExporter, error) {
+	if cfg.MetricsEndpoint != "" {
+		_, err := url.Parse(cfg.MetricsEndpoint)
 		if err != nil {
 			return nil, errors.New("endpoint must be a valid URL")
 		}
 	}
 
+	metricFilter, err := cfg.MetricFilter.buildFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	var keyFile *apiKeyFile
+	if cfg.APIKeyFile != "" {
+		keyFile = newAPIKeyFile(cfg.APIKeyFile, cfg.resolvedKeyType(), cfg.APIKeyFileReloadInterval, set.Logger)
+	}
+
+	var breaker *circuitbreaker.Breaker
+	if cfg.CircuitBreaker.Enabled {
+		threshold, coolDown := cfg.CircuitBreaker.FailureThreshold, cfg.CircuitBreaker.CoolDown
+		if threshold == 0 {
+			threshold = defaultCircuitBreakerFailureThreshold
+		}
+		if coolDown == 0 {
+			coolDown = defaultCircuitBreakerCoolDown
+		}
+		breaker = circuitbreaker.New(threshold, coolDown)
+	}
+
+	var selector *failover.Selector
+	if n := len(cfg.Failover.Endpoints); n > 0 {
+		recovery := cfg.Failover.RecoveryInterval
+		if recovery == 0 {
+			recovery = defaultFailoverRecoveryInterval
+		}
+		selector = failover.New(n+1, recovery)
+	}
+
 	userAgent := fmt.Sprintf("%s/%s (%s/%s)",
 		set.BuildInfo.Description, set.BuildInfo.Version, runtime.GOOS, runtime.GOARCH)
 
 	// client construction is deferred to start
 	return &baseExporter{
-		otlpExporter:     otlpExporter,
+		signal:           signal,
 		config:           &cfg,
 		logger:           set.Logger,
 		userAgent:        userAgent,
 		settings:         set,
 		telemetryBuilder: telemetryBuilder,
+		metricFilter:     metricFilter,
+		apiKeyFile:       keyFile,
+		breaker:          breaker,
+		limiter:          sharedLimiters.acquire(set.ID, cfg.RateLimit),
+		failover:         selector,
 	}, nil
 }
-func (e *baseExporter) Capabilities() consumer.Capabilities {
-	return (*e.otlpExporter).Capabilities()
-}
-
-func (e *baseExporter) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
-	return (*e.otlpExporter).ConsumeMetrics(ctx, md)
-}
 
 // start actually creates the HTTP client. The client construction is deferred till this point as this
 // is the only place we get hold of Extensions which are required to construct auth round tripper.
 func (e *baseExporter) Start(ctx context.Context, host component.Host) error {
-	client, err := e.config.OtlpHttpExporterConfig.ClientConfig.ToClient(ctx, host, e.settings.TelemetrySettings)
+	client, err := e.config.ClientConfig.ToClient(ctx, host, e.settings.TelemetrySettings)
 	if err != nil {
 		return err
 	}
 	e.client = client
+	if e.apiKeyFile != nil {
+		if err := e.apiKeyFile.start(); err != nil {
+			return err
+		}
+	}
+	if e.config.StartupCheck {
+		return e.checkConnectivity(ctx, host)
+	}
+	return nil
+}
+
+// checkConnectivity posts an empty batch to the API the exporter's signal is sent
+// to. It fails if the API rejects the request for good, such as for an invalid API
+// key. Failures that can go away, like timeouts or a 503 during an incident, are
+// reported as a recoverable component status instead, so they don't keep the
+// collector from starting.
+func (e *baseExporter) checkConnectivity(ctx context.Context, host component.Host) error {
+	if e.apiKey(ctx) == "" {
+		// The key only arrives with the request metadata.
+		e.logger.Warn("Skipping startup check, no API key is configured")
+		return nil
+	}
+	url, request, headers := e.probeRequest(ctx)
+	if url == "" {
+		// Metrics are all routed to accounts.
+		e.logger.Warn("Skipping startup check, no endpoint is configured")
+		return nil
+	}
+	if err := e.export(ctx, url, request, e.nrAPIResponseHandler, 0, headers); err != nil {
+		err = fmt.Errorf("startup check failed: %w", err)
+		if consumererror.IsPermanent(err) {
+			return err
+		}
+		e.logger.Warn("Startup check failed, the endpoint may be unavailable", zap.String("endpoint", url), zap.Error(err))
+		componentstatus.ReportStatus(host, componentstatus.NewRecoverableErrorEvent(err))
+		return nil
+	}
+	e.logger.Info("Startup check succeeded", zap.String("endpoint", url))
+	return nil
+}
+
+// probeRequest returns the URL the exporter's signal is sent to, with an empty
+// payload in that API's format.
+func (e *baseExporter) probeRequest(ctx context.Context) (string, []byte, map[string]string) {
+	switch e.signal {
+	case pipeline.SignalLogs:
+		if e.config.LogsFormat == formatLog {
+			return e.logAPIEndpoint(ctx), []byte(`[{"logs":[]}]`), nil
+		}
+		return e.eventsEndpoint(ctx, e.config.LogsEndpoint), []byte("[]"), nil
+	case pipeline.SignalTraces:
+		if e.config.TracesFormat == formatTrace {
+			return e.traceAPIEndpoint(ctx), []byte(`[{"spans":[]}]`), traceAPIHeaders
+		}
+		return e.eventsEndpoint(ctx, e.config.TracesEndpoint), []byte("[]"), nil
+	default:
+		if e.config.MetricsFormat == formatMetric {
+			return e.metricAPIEndpoint(ctx), []byte(`[{"metrics":[]}]`), nil
+		}
+		return e.eventsEndpoint(ctx, e.config.MetricsEndpoint), []byte("[]"), nil
+	}
+}
+
+// Shutdown stops reloading the API key file and releases the shared rate limiter.
+func (e *baseExporter) Shutdown(context.Context) error {
+	if e.apiKeyFile != nil {
+		e.apiKeyFile.shutdown()
+	}
+	if e.limiter != nil {
+		sharedLimiters.release(e.settings.ID)
+	}
 	return nil
 }
 
-// Shutdown executes the provided ShutdownFunc if it's not nil.
-func (e *baseExporter) Shutdown(ctx context.Context) error {
-	return (*e.otlpExporter).Shutdown(ctx)
+// apiKey returns the key requests are sent with: a per-request key set on the
+// context, else the current key from api_key_file, else api_key.
+func (e *baseExporter) apiKey(ctx context.Context) configopaque.String {
+	if key, ok := apiKeyFromContext(ctx); ok {
+		return key
+	}
+	if e.apiKeyFile != nil {
+		return e.apiKeyFile.get()
+	}
+	return e.config.APIKey
 }
 
 func (e *baseExporter) pushMetrics(ctx context.Context, md pmetric.Metrics) error {
 	//tr := pmetricotlp.NewExportRequestFromMetrics(md)
+	md = e.metricFilter.FilterMetrics(md)
+	if md.MetricCount() == 0 {
+		e.logger.Debug("MetricsExporter: all metrics filtered out, nothing to export")
+		return nil
+	}
 
 	e.logger.Info("MetricsExporter",
 		zap.Int("resource metrics", md.ResourceMetrics().Len()),
 		zap.Int("metrics", md.MetricCount()),
 		zap.Int("data points", md.DataPointCount()))
 
-	var err error
-	var request []byte
+	if len(e.config.Accounts) > 0 {
+		return e.pushMetricsByAccount(ctx, md)
+	}
+	ctx, endpoint := e.withClientCredentials(ctx)
+	return e.sendMetrics(ctx, md, endpoint)
+}
 
-	// Build a NR event payload from the metrics data
-	var counter int // number of events in the payload
-	request, counter = metrictoevent.BuildNREventPayload(e.logger, md, e.config.eventType)
+// sendMetrics converts md to the configured format and sends it. A non empty
+// endpoint overrides the configured destination.
+func (e *baseExporter) sendMetrics(ctx context.Context, md pmetric.Metrics, endpoint string) error {
+	if e.config.MetricsFormat == formatMetric {
+		// Build a NR Metric API payload from the metrics data
+		request, counter, err := metrictonrmetric.BuildNRMetricPayload(e.logger, md)
+		if err != nil {
+			return consumererror.NewPermanent(fmt.Errorf("failed to build Metric API payload: %w", err))
+		}
+		if counter == 0 {
+			e.logger.Debug("MetricsExporter: no data points can be sent to the Metric API")
+			return nil
+		}
 
-	e.logger.Debug("MetricsExporter", zap.Int("compressed size", len(request)))
+		e.logger.Debug("MetricsExporter", zap.Int("size", len(request)))
 
-	if err != nil {
-		return consumererror.NewPermanent(err)
+		if endpoint == "" {
+			endpoint = e.metricAPIEndpoint(ctx)
+		}
+		return e.export(ctx, endpoint, request, e.nrAPIResponseHandler, counter, nil)
+	}
+
+	// Build NR events from the metrics data
+	events := metrictoevent.MetricsToNREvents(e.logger, md, e.config.EventType, e.config.NormalizeUnits)
+	if e.config.EventIDs {
+		idempotency.AddEventIDs(events)
+	}
+
+	if endpoint == "" {
+		endpoint = e.eventsEndpoint(ctx, e.config.MetricsEndpoint)
+	}
+	retry, err := exportEventsInChunks(ctx, e, endpoint, events)
+	return retryMetrics(err, md, retry)
+}
+
+func (e *baseExporter) pushLogs(ctx context.Context, ld plog.Logs) error {
+	e.logger.Info("LogsExporter",
+		zap.Int("resource logs", ld.ResourceLogs().Len()),
+		zap.Int("log records", ld.LogRecordCount()))
+
+	if ld.LogRecordCount() == 0 {
+		return nil
+	}
+
+	if e.config.LogsFormat == formatLog {
+		// Build a NR Log API payload from the log records
+		request, counter := logtonrlog.BuildNRLogPayload(e.logger, ld)
+
+		e.logger.Debug("LogsExporter", zap.Int("size", len(request)))
+
+		return e.export(ctx, e.logAPIEndpoint(ctx), request, e.nrAPIResponseHandler, counter, nil)
+	}
+
+	// Build NR events from the log records
+	events := logtoevent.LogsToNREvents(e.logger, ld, e.config.EventType)
+	if e.config.EventIDs {
+		idempotency.AddEventIDs(events)
+	}
+
+	retry, err := exportEventsInChunks(ctx, e, e.eventsEndpoint(ctx, e.config.LogsEndpoint), events)
+	return retryLogs(err, ld, retry)
+}
+
+func (e *baseExporter) pushTraces(ctx context.Context, td ptrace.Traces) error {
+	e.logger.Info("TracesExporter",
+		zap.Int("resource spans", td.ResourceSpans().Len()),
+		zap.Int("spans", td.SpanCount()))
+
+	if td.SpanCount() == 0 {
+		return nil
+	}
+
+	if e.config.TracesFormat == formatTrace {
+		// Build a NR Trace API payload from the spans
+		request, counter := spantonrtrace.BuildNRTracePayload(e.logger, td)
+
+		e.logger.Debug("TracesExporter", zap.Int("size", len(request)))
+
+		return e.export(ctx, e.traceAPIEndpoint(ctx), request, e.nrAPIResponseHandler, counter, traceAPIHeaders)
+	}
+
+	// Build NR events from the spans
+	events := spantoevent.TracesToNREvents(e.logger, td, e.config.EventType, e.config.SpanEvents, e.config.SpanLinks)
+	if e.config.EventIDs {
+		idempotency.AddEventIDs(events)
 	}
-	return e.export(ctx, e.config.OtlpHttpExporterConfig.MetricsEndpoint, request, e.metricsPartialSuccessHandler, counter)
+
+	retry, err := exportEventsInChunks(ctx, e, e.eventsEndpoint(ctx, e.config.TracesEndpoint), events)
+	return retryTraces(err, td, retry, e.config.SpanEvents, e.config.SpanLinks)
+}
+
+// region returns the region of the key requests on ctx are sent with, so a key read
+// from api_key_file or sent in client metadata is sent to its own datacenter.
+func (e *baseExporter) region(ctx context.Context) string {
+	return e.config.regionForKey(e.apiKey(ctx))
+}
+
+// eventsEndpoint returns the Event API URL for a signal, in the region of the key in use.
+func (e *baseExporter) eventsEndpoint(ctx context.Context, signalEndpoint string) string {
+	return e.config.eventsEndpoint(signalEndpoint, e.region(ctx))
 }
 
-func (e *baseExporter) export(ctx context.Context, url string, request []byte, partialSuccessHandler partialSuccessHandler, counter int) error {
-	start := time.Now()
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(request))
+// logAPIEndpoint returns the Log API URL, defaulting to the region's endpoint.
+func (e *baseExporter) logAPIEndpoint(ctx context.Context) string {
+	if e.config.LogsEndpoint != "" {
+		return e.config.LogsEndpoint
+	}
+	return logAPIEndpoint(e.region(ctx))
+}
+
+// metricAPIEndpoint returns the Metric API URL, defaulting to the region's endpoint.
+func (e *baseExporter) metricAPIEndpoint(ctx context.Context) string {
+	if e.config.MetricsEndpoint != "" {
+		return e.config.MetricsEndpoint
+	}
+	return metricAPIEndpoint(e.region(ctx))
+}
+
+// traceAPIEndpoint returns the Trace API URL, defaulting to the region's endpoint.
+func (e *baseExporter) traceAPIEndpoint(ctx context.Context) string {
+	if e.config.TracesEndpoint != "" {
+		return e.config.TracesEndpoint
+	}
+	return traceAPIEndpoint(e.region(ctx))
+}
+
+func (e *baseExporter) export(ctx context.Context, url string, request []byte, responseHandler responseHandler, counter int, headers map[string]string) error {
+	clientConfig := e.config.ClientConfig
+	method := string(clientConfig.Compression)
+	body, err := compression.Compress(method, int(clientConfig.CompressionParams.Level), request)
 	if err != nil {
 		return consumererror.NewPermanent(err)
 	}
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	// Retries of a payload carry the same ID so a proxy can reject the duplicates.
+	header.Set(headerRequestID, idempotency.RequestID(request))
+	apiKey := e.apiKey(ctx)
+	header.Set(e.config.apiKeyHeader(), string(apiKey))
+	if encoding := compression.ContentEncoding(method); encoding != "" {
+		header.Set("Content-Encoding", encoding)
+	}
+	for k, v := range headers {
+		header.Set(k, v)
+	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Api-Key", e.config.APIKey)
-	req.Header.Set("Content-Encoding", "gzip")
+	if e.breaker != nil {
+		if ok, wait := e.breaker.Allow(); !ok {
+			// Fail fast while the endpoint is failing, the batch is retried once the breaker half-opens.
+			return exporterhelper.NewThrottleRetry(fmt.Errorf("circuit breaker open, not sending to %s", url), wait)
+		}
+	}
+
+	// Rate limit tokens are only spent on requests the breaker lets through.
+	if err := e.waitForRateLimit(ctx, url, counter, len(body)); err != nil {
+		e.releaseBreaker()
+		return err
+	}
 
-	//e.logger.Debug("Headers", zap.Any("headers", req.Header))
-	resp, err := e.client.Do(req)
-	fmt.Println("url is", url)
+	e.logger.Debug("Headers", zap.Any("headers", redactedHeaders(header)))
+	req, resp, err := e.send(ctx, url, body, header, counter)
+	if req == nil {
+		e.releaseBreaker()
+		return consumererror.NewPermanent(err)
+	}
+	url = req.URL.String()
 	if err != nil {
+		e.recordBreakerResult(true)
 		return fmt.Errorf("failed to make an HTTP request: %w", err)
 	}
-	e.recordMetrics(time.Since(start), counter, req, nil)
+	e.recordBreakerResult(resp.StatusCode >= 500)
 
 	defer func() {
 		// Discard any remaining response body when we are done reading.
@@ -146,18 +430,18 @@ func (e *baseExporter) export(ctx context.Context, url string, request []byte, p
 	}()
 
 	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
-		return handlePartialSuccessResponse(resp, partialSuccessHandler)
+		return handleSuccessResponse(resp, responseHandler, apiKey)
 	}
 
-	respStatus := readResponseStatus(resp)
+	respMessage := redactAPIKey(readResponseError(resp), apiKey)
 
-	// Format the error message. Use the status if it is present in the response.
+	// Format the error message. Use the message if it is present in the response.
 	var errString string
 	var formattedErr error
-	if respStatus != nil {
+	if respMessage != "" {
 		errString = fmt.Sprintf(
-			"error exporting items, request to %s responded with HTTP Status Code %d, Message=%s, Details=%v",
-			url, resp.StatusCode, respStatus.Message, respStatus.Details)
+			"error exporting items, request to %s responded with HTTP Status Code %d, Message=%s",
+			url, resp.StatusCode, respMessage)
 	} else {
 		errString = fmt.Sprintf(
 			"error exporting items, request to %s responded with HTTP Status Code %d",
@@ -165,28 +449,77 @@ func (e *baseExporter) export(ctx context.Context, url string, request []byte, p
 	}
 	formattedErr = httphelper.NewStatusFromMsgAndHTTPCode(errString, resp.StatusCode).Err()
 
-	if isRetryableStatusCode(resp.StatusCode) {
-		// A retry duration of 0 seconds will trigger the default backoff policy
-		// of our caller (retry handler).
-		retryAfter := 0
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		// Callers that can split the payload look for errPayloadTooLarge.
+		return consumererror.NewPermanent(fmt.Errorf("%w: %w", errPayloadTooLarge, formattedErr))
+	}
 
-		// Check if the server is overwhelmed.
+	if e.isRetryableStatusCode(resp.StatusCode) {
+		// Honor the server's Retry-After on any retryable response, not just the
+		// 429 and 503 throttling responses the OTLP spec describes, since proxies
+		// in front of New Relic send it with their own transient errors.
 		// See spec https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/protocol/otlp.md#otlphttp-throttling
-		isThrottleError := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
-		if val := resp.Header.Get(headerRetryAfter); isThrottleError && val != "" {
-			if seconds, err2 := strconv.Atoi(val); err2 == nil {
-				retryAfter = seconds
-			}
+		retryAfter := retryAfterDelay(resp.Header.Get(headerRetryAfter), time.Now(), e.config.MaxRetryAfter)
+		return exporterhelper.NewThrottleRetry(formattedErr, retryAfter)
+	}
+	return consumererror.NewPermanent(formattedErr)
+}
+
+// waitForRateLimit applies the rate limits to a request of the given number of events
+// and bytes, either waiting until it is allowed or failing it with a retryable error.
+func (e *baseExporter) waitForRateLimit(ctx context.Context, url string, events, size int) error {
+	if e.limiter == nil {
+		return nil
+	}
+	if e.config.RateLimit.Mode == rateLimitThrottle {
+		if ok, wait := e.limiter.TryTake(events, size); !ok {
+			return exporterhelper.NewThrottleRetry(fmt.Errorf("rate limit exceeded, not sending to %s", url), wait)
 		}
+		return nil
+	}
+	waited, err := e.limiter.Wait(ctx, events, size)
+	if waited > 0 {
+		e.telemetryBuilder.ExporterRateLimitWaitDuration.Add(context.Background(), waited.Milliseconds(),
+			metric.WithAttributes(attribute.String("endpoint", url), attribute.String("exporter", "nreventexporter")))
+	}
+	if err != nil {
+		return fmt.Errorf("waiting for rate limit: %w", err)
+	}
+	return nil
+}
 
-		return exporterhelper.NewThrottleRetry(formattedErr, time.Duration(retryAfter)*time.Second)
+// recordBreakerResult reports the outcome of a request to the circuit breaker, if enabled.
+func (e *baseExporter) recordBreakerResult(failed bool) {
+	if e.breaker == nil {
+		return
+	}
+	if failed {
+		e.breaker.Failure()
+		return
+	}
+	e.breaker.Success()
+}
+
+// releaseBreaker tells the circuit breaker, if enabled, that a request it allowed
+// was not sent.
+func (e *baseExporter) releaseBreaker() {
+	if e.breaker != nil {
+		e.breaker.Release()
 	}
-	return consumererror.NewPermanent(formattedErr)
 }
 
-// Determine if the status code is retryable according to the specification.
+// Determine if the status code is retryable. Unless retryable_status_codes is set
+// this follows the specification.
 // For more, see https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/protocol/otlp.md#failures-1
-func isRetryableStatusCode(code int) bool {
+func (e *baseExporter) isRetryableStatusCode(code int) bool {
+	if len(e.config.RetryableStatusCodes) > 0 {
+		for _, c := range e.config.RetryableStatusCodes {
+			if c == code {
+				return true
+			}
+		}
+		return false
+	}
 	switch code {
 	case http.StatusTooManyRequests:
 		return true
@@ -201,6 +534,43 @@ func isRetryableStatusCode(code int) bool {
 	}
 }
 
+// retryAfterDelay returns how long to wait before retrying according to a Retry-After
+// header value, capped at maxDelay when it is set. A retry duration of 0 seconds, for
+// a missing or invalid header, will trigger the default backoff policy of our caller
+// (retry handler).
+func retryAfterDelay(val string, now time.Time, maxDelay time.Duration) time.Duration {
+	if val == "" {
+		return 0
+	}
+	delay, ok := parseRetryAfter(val, now)
+	if !ok {
+		return 0
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// parseRetryAfter parses a Retry-After header value, which is either a number of
+// seconds or an HTTP-date. Dates in the past yield a zero delay.
+// See https://www.rfc-editor.org/rfc/rfc9110#field.retry-after
+func parseRetryAfter(val string, now time.Time) (time.Duration, bool) {
+	if seconds, err := strconv.Atoi(val); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(val); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
 func readResponseBody(resp *http.Response) ([]byte, error) {
 	if resp.ContentLength == 0 {
 		return nil, nil
@@ -211,12 +581,12 @@ func readResponseBody(resp *http.Response) ([]byte, error) {
 	// if maxRead == -1, the ContentLength header has not been sent, so read up to
 	// the maximum permitted body size. If it is larger than the permitted body
 	// size, still try to read from the body in case the value is an error. If the
-	// body is larger than the maximum size, proto unmarshaling will likely fail.
+	// body is larger than the maximum size, JSON decoding will likely fail.
 	if maxRead == -1 || maxRead > maxHTTPResponseReadBytes {
 		maxRead = maxHTTPResponseReadBytes
 	}
-	protoBytes := make([]byte, maxRead)
-	n, err := io.ReadFull(resp.Body, protoBytes)
+	respBytes := make([]byte, maxRead)
+	n, err := io.ReadFull(resp.Body, respBytes)
 
 	// No bytes read and an EOF error indicates there is no body to read.
 	if n == 0 && (err == nil || errors.Is(err, io.EOF)) {
@@ -231,70 +601,103 @@ func readResponseBody(resp *http.Response) ([]byte, error) {
 		return nil, err
 	}
 
-	return protoBytes[:n], nil
+	return respBytes[:n], nil
 }
 
-// Read the response and decode the status.Status from the body.
-// Returns nil if the response is empty or cannot be decoded.
-func readResponseStatus(resp *http.Response) *status.Status {
-	var respStatus *status.Status
-	if resp.StatusCode >= 400 && resp.StatusCode <= 599 {
-		// Request failed. Read the body. OTLP spec says:
-		// "Response body for all HTTP 4xx and HTTP 5xx responses MUST be a
-		// Protobuf-encoded Status message that describes the problem."
-		respBytes, err := readResponseBody(resp)
-		if err != nil {
-			return nil
-		}
+// Read the response and extract the error message from the body. The New Relic
+// APIs reply with a JSON error document, proxies in between often with plain text.
+// Returns "" if the response is empty or holds no message.
+func readResponseError(resp *http.Response) string {
+	if resp.StatusCode < 400 || resp.StatusCode > 599 {
+		return ""
+	}
+	respBytes, err := readResponseBody(resp)
+	if err != nil || len(respBytes) == 0 {
+		return ""
+	}
 
-		// Decode it as Status struct. See https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/protocol/otlp.md#failures
-		respStatus = &status.Status{}
-		err = proto.Unmarshal(respBytes, respStatus)
-		if err != nil {
-			return nil
+	if isJSONContentType(resp.Header.Get("Content-Type")) || json.Valid(respBytes) {
+		var nrResp nrAPIResponse
+		if err := json.Unmarshal(respBytes, &nrResp); err == nil {
+			return nrResp.errorMessage()
 		}
 	}
 
-	return respStatus
+	msg := strings.TrimSpace(string(respBytes))
+	if len(msg) > maxErrorMessageLength {
+		msg = msg[:maxErrorMessageLength] + "..."
+	}
+	return msg
 }
 
-func handlePartialSuccessResponse(resp *http.Response, partialSuccessHandler partialSuccessHandler) error {
+// handleSuccessResponse passes the body of a 2xx response to responseHandler, with
+// any echo of apiKey redacted as the handler may put the body into errors and logs.
+func handleSuccessResponse(resp *http.Response, responseHandler responseHandler, apiKey configopaque.String) error {
 	bodyBytes, err := readResponseBody(resp)
 	if err != nil {
 		return err
 	}
+	if bodyBytes != nil {
+		bodyBytes = []byte(redactAPIKey(string(bodyBytes), apiKey))
+	}
 
-	return partialSuccessHandler(bodyBytes, resp.Header.Get("Content-Type"))
+	return responseHandler(bodyBytes, resp.Header.Get("Content-Type"))
 }
 
-type partialSuccessHandler func(bytes []byte, contentType string) error
+type responseHandler func(bytes []byte, contentType string) error
+
+// nrAPIResponse is the JSON body returned by the New Relic ingest APIs. The Event API
+// replies with success and uuid, the Log, Metric and Trace APIs with requestId. Errors
+// are reported either as a string or as an object with a message.
+type nrAPIResponse struct {
+	Success   *bool           `json:"success"`
+	UUID      string          `json:"uuid"`
+	RequestID string          `json:"requestId"`
+	Error     json.RawMessage `json:"error"`
+	Message   string          `json:"message"`
+}
 
-func (e *baseExporter) metricsPartialSuccessHandler(protoBytes []byte, contentType string) error {
-	if protoBytes == nil {
-		return nil
-	}
-	exportResponse := pmetricotlp.NewExportResponse()
-	switch contentType {
-	case protobufContentType:
-		err := exportResponse.UnmarshalProto(protoBytes)
-		if err != nil {
-			return fmt.Errorf("error parsing protobuf response: %w", err)
+// errorMessage returns the error reported in the response, or "" if there is none.
+func (r *nrAPIResponse) errorMessage() string {
+	if len(r.Error) > 0 {
+		var msg string
+		if err := json.Unmarshal(r.Error, &msg); err == nil {
+			return msg
 		}
-	case jsonContentType:
-		err := exportResponse.UnmarshalJSON(protoBytes)
-		if err != nil {
-			return fmt.Errorf("error parsing json response: %w", err)
+		var obj struct {
+			Message string `json:"message"`
 		}
-	default:
-		return nil
+		if err := json.Unmarshal(r.Error, &obj); err == nil && obj.Message != "" {
+			return obj.Message
+		}
+		return string(r.Error)
 	}
+	return r.Message
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == jsonContentType
+}
 
-	partialSuccess := exportResponse.PartialSuccess()
-	if !(partialSuccess.ErrorMessage() == "" && partialSuccess.RejectedDataPoints() == 0) {
-		e.logger.Warn("Partial success response",
-			zap.String("message", exportResponse.PartialSuccess().ErrorMessage()),
-			zap.Int64("dropped_data_points", exportResponse.PartialSuccess().RejectedDataPoints()),
-		)
+// nrAPIResponseHandler handles successful responses from the New Relic ingest APIs.
+// The request id is logged so a payload can be traced through New Relic support.
+func (e *baseExporter) nrAPIResponseHandler(body []byte, contentType string) error {
+	if body == nil || !isJSONContentType(contentType) {
+		return nil
+	}
+	var resp nrAPIResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		e.logger.Debug("Unable to parse New Relic API response", zap.Error(err))
+		return nil
+	}
+	if resp.UUID != "" || resp.RequestID != "" {
+		e.logger.Debug("New Relic API accepted request",
+			zap.String("uuid", resp.UUID),
+			zap.String("request_id", resp.RequestID))
+	}
+	if resp.Success != nil && !*resp.Success {
+		return consumererror.NewPermanent(fmt.Errorf("New Relic API rejected the request: %s", resp.errorMessage()))
 	}
 	return nil
 }
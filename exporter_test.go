@@ -0,0 +1,272 @@
+package nreventexporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configcompression"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+func TestExportReleasesBreakerProbeWhenRequestIsNotSent(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	e := newTestExporter(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}, func(cfg *Config) {
+		cfg.CircuitBreaker = CircuitBreakerConfig{Enabled: true, FailureThreshold: 1, CoolDown: time.Millisecond}
+	})
+	url := e.config.Endpoint
+
+	require.Error(t, e.export(context.Background(), url, []byte("[]"), e.nrAPIResponseHandler, 0, nil))
+	time.Sleep(2 * time.Millisecond)
+
+	// The probe can't even be built into a request.
+	require.Error(t, e.export(context.Background(), "http://[::1", []byte("[]"), e.nrAPIResponseHandler, 0, nil))
+
+	status.Store(http.StatusOK)
+	require.NoError(t, e.export(context.Background(), url, []byte("[]"), e.nrAPIResponseHandler, 0, nil))
+}
+
+func TestStartupCheckProbesSignalEndpointInItsFormat(t *testing.T) {
+	type probe struct{ path, body, dataFormat string }
+	probes := make(chan probe, 3)
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		probes <- probe{r.URL.Path, string(body), r.Header.Get("Data-Format")}
+	}))
+	defer srv.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.APIKey = "NRAK-test"
+	cfg.KeyType = keyTypeUser
+	cfg.Compression = configcompression.Type("none")
+	cfg.StartupCheck = true
+	cfg.MetricsFormat = formatMetric
+	cfg.MetricsEndpoint = srv.URL + "/metric/v1"
+	cfg.LogsEndpoint = srv.URL + "/v1/accounts/1/events"
+	cfg.TracesFormat = formatTrace
+	cfg.TracesEndpoint = srv.URL + "/trace/v1"
+	require.NoError(t, cfg.Validate())
+
+	factory := NewFactory()
+	set := exportertest.NewNopSettings()
+	metrics, err := factory.CreateMetrics(context.Background(), set, cfg)
+	require.NoError(t, err)
+	logs, err := factory.CreateLogs(context.Background(), set, cfg)
+	require.NoError(t, err)
+	traces, err := factory.CreateTraces(context.Background(), set, cfg)
+	require.NoError(t, err)
+
+	for _, c := range []component.Component{metrics, logs, traces} {
+		require.NoError(t, c.Start(context.Background(), componenttest.NewNopHost()))
+		require.NoError(t, c.Shutdown(context.Background()))
+	}
+	assert.Equal(t, probe{"/metric/v1", `[{"metrics":[]}]`, ""}, <-probes)
+	assert.Equal(t, probe{"/v1/accounts/1/events", "[]", ""}, <-probes)
+	assert.Equal(t, probe{"/trace/v1", `[{"spans":[]}]`, "newrelic"}, <-probes)
+}
+
+func TestStartupCheckFailsOnRejectedKey(t *testing.T) {
+	e := newTestExporter(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}, nil)
+	e.config.StartupCheck = true
+
+	err := e.Start(context.Background(), componenttest.NewNopHost())
+	assert.ErrorContains(t, err, "startup check failed")
+	assert.ErrorContains(t, err, "403")
+}
+
+// statusHost records the component status events reported to it.
+type statusHost struct {
+	component.Host
+	events []*componentstatus.Event
+}
+
+func (h *statusHost) Report(event *componentstatus.Event) {
+	h.events = append(h.events, event)
+}
+
+func TestStartupCheckReportsUnavailableEndpointAsRecoverable(t *testing.T) {
+	e := newTestExporter(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, nil)
+	e.config.StartupCheck = true
+
+	host := &statusHost{Host: componenttest.NewNopHost()}
+	require.NoError(t, e.Start(context.Background(), host))
+	require.Len(t, host.events, 1)
+	assert.Equal(t, componentstatus.StatusRecoverableError, host.events[0].Status())
+	assert.ErrorContains(t, host.events[0].Err(), "503")
+}
+
+func TestRegionFollowsKeyFromFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	euKey := "eu01" + strings.Repeat("x", licenseKeyLength-4)
+	require.NoError(t, os.WriteFile(keyFile, []byte(euKey), 0o600))
+
+	e := newTestExporter(t, nil, func(cfg *Config) {
+		cfg.APIKey = ""
+		cfg.APIKeyFile = keyFile
+		cfg.KeyType = keyTypeLicense
+		cfg.Endpoint = ""
+		cfg.AccountID = "1"
+	})
+
+	ctx := context.Background()
+	assert.Equal(t, "https://insights-collector.eu01.nr-data.net/v1/accounts/1/events", e.eventsEndpoint(ctx, ""))
+	assert.Equal(t, "https://log-api.eu.newrelic.com/log/v1", e.logAPIEndpoint(ctx))
+
+	usKey := configopaque.String(strings.Repeat("x", licenseKeyLength))
+	assert.Equal(t, "https://log-api.newrelic.com/log/v1", e.logAPIEndpoint(contextWithAPIKey(ctx, usKey)),
+		"a per-request key picks its own region")
+}
+
+func TestRetryAfterDelay(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		val      string
+		maxDelay time.Duration
+		want     time.Duration
+	}{
+		{name: "missing", val: "", want: 0},
+		{name: "delta seconds", val: "120", want: 2 * time.Minute},
+		{name: "HTTP date", val: "Wed, 01 May 2024 12:00:30 GMT", want: 30 * time.Second},
+		{name: "past date", val: "Wed, 01 May 2024 11:59:00 GMT", want: 0},
+		{name: "negative", val: "-5", want: 0},
+		{name: "garbage", val: "soon", want: 0},
+		{name: "capped", val: "3600", maxDelay: time.Minute, want: time.Minute},
+		{name: "below cap", val: "10", maxDelay: time.Minute, want: 10 * time.Second},
+		{name: "capped date", val: "Wed, 01 May 2024 13:00:00 GMT", maxDelay: time.Minute, want: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, retryAfterDelay(tt.val, now, tt.maxDelay))
+		})
+	}
+}
+
+func TestReadResponseError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		want        string
+	}{
+		{name: "string error", status: http.StatusForbidden, contentType: "application/json", body: `{"error":"invalid license key"}`, want: "invalid license key"},
+		{name: "object error", status: http.StatusBadRequest, contentType: "application/json; charset=utf-8", body: `{"error":{"message":"invalid payload"}}`, want: "invalid payload"},
+		{name: "message", status: http.StatusBadRequest, contentType: "application/json", body: `{"message":"missing eventType"}`, want: "missing eventType"},
+		{name: "JSON without content type", status: http.StatusBadRequest, body: `{"error":"bad"}`, want: "bad"},
+		{name: "plain text proxy body", status: http.StatusBadGateway, contentType: "text/html", body: "  <html>Bad Gateway</html>\n", want: "<html>Bad Gateway</html>"},
+		{name: "long body", status: http.StatusBadGateway, contentType: "text/plain", body: strings.Repeat("x", maxErrorMessageLength+10), want: strings.Repeat("x", maxErrorMessageLength) + "..."},
+		{name: "empty body", status: http.StatusServiceUnavailable, want: ""},
+		{name: "not an error status", status: http.StatusAccepted, body: `{"error":"ignored"}`, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if tt.contentType != "" {
+				rec.Header().Set("Content-Type", tt.contentType)
+			}
+			rec.WriteHeader(tt.status)
+			_, _ = rec.WriteString(tt.body)
+			assert.Equal(t, tt.want, readResponseError(rec.Result()))
+		})
+	}
+}
+
+func TestNRAPIResponseHandler(t *testing.T) {
+	e := &baseExporter{logger: zap.NewNop()}
+
+	assert.NoError(t, e.nrAPIResponseHandler([]byte(`{"success":true,"uuid":"abc"}`), "application/json"))
+	assert.NoError(t, e.nrAPIResponseHandler([]byte(`{"requestId":"abc"}`), "application/json"))
+	assert.NoError(t, e.nrAPIResponseHandler(nil, "application/json"))
+	assert.NoError(t, e.nrAPIResponseHandler([]byte("accepted"), "text/plain"), "bodies that aren't JSON are not decoded")
+	assert.NoError(t, e.nrAPIResponseHandler([]byte("{"), "application/json"), "an undecodable body doesn't fail a delivered request")
+
+	err := e.nrAPIResponseHandler([]byte(`{"success":false,"error":"invalid event"}`), "application/json")
+	assert.True(t, consumererror.IsPermanent(err))
+	assert.EqualError(t, err, "Permanent error: New Relic API rejected the request: invalid event")
+
+	err = e.nrAPIResponseHandler([]byte(`{"success":false,"error":{"message":"too many attributes"}}`), "application/json")
+	assert.EqualError(t, err, "Permanent error: New Relic API rejected the request: too many attributes")
+}
+
+func TestPushTracesToTraceAPISendsDataFormatHeaders(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	e := newTestExporter(t, func(_ http.ResponseWriter, r *http.Request) {
+		requests <- r
+	}, func(cfg *Config) {
+		cfg.TracesFormat = formatTrace
+		cfg.TracesEndpoint = strings.TrimSuffix(cfg.Endpoint, "/v1/accounts/1/events") + "/trace/v1"
+	})
+
+	td := ptrace.NewTraces()
+	td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("span")
+	require.NoError(t, e.pushTraces(context.Background(), td))
+
+	r := <-requests
+	assert.Equal(t, "/trace/v1", r.URL.Path)
+	assert.Equal(t, "newrelic", r.Header.Get("Data-Format"))
+	assert.Equal(t, "1", r.Header.Get("Data-Format-Version"))
+}
+
+func TestExportCompressesPayloadOnce(t *testing.T) {
+	for _, method := range []configcompression.Type{configcompression.TypeGzip, configcompression.TypeDeflate} {
+		t.Run(string(method), func(t *testing.T) {
+			type request struct {
+				encoding string
+				body     []byte
+			}
+			requests := make(chan request, 1)
+			e := newTestExporter(t, func(_ http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				requests <- request{r.Header.Get("Content-Encoding"), body}
+			}, func(cfg *Config) {
+				cfg.Compression = method
+			})
+
+			require.NoError(t, e.pushLogs(context.Background(), testLogs(1)))
+
+			r := <-requests
+			assert.Equal(t, string(method), r.encoding)
+			var reader io.ReadCloser
+			var err error
+			if method == configcompression.TypeGzip {
+				reader, err = gzip.NewReader(bytes.NewReader(r.body))
+			} else {
+				reader, err = zlib.NewReader(bytes.NewReader(r.body))
+			}
+			require.NoError(t, err)
+			payload, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			// Decompressing once yields the JSON events, so the HTTP client didn't
+			// compress the body a second time.
+			assert.True(t, json.Valid(payload), "payload: %q", payload)
+		})
+	}
+}
@@ -4,59 +4,119 @@
 package nreventexporter // import "github.com/shelson/nreventexporter"
 import (
 	"context"
-	"fmt"
+	"time"
 
 	"github.com/jwang25/nreventexporter/internal/metadata"
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configcompression"
+	"go.opentelemetry.io/collector/config/confighttp"
 	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
-	"go.opentelemetry.io/collector/exporter/otlphttpexporter"
+	"go.opentelemetry.io/collector/pipeline"
 )
 
-// NewFactory creates a factory for OTLP exporter.
+// defaultEventType is the eventType of the events when event_type is not set.
+const defaultEventType = "OtelEvent"
+
+// NewFactory creates a factory for the New Relic event exporter.
 func NewFactory() exporter.Factory {
-	otlpHttpExporterFactory := otlphttpexporter.NewFactory()
 	return exporter.NewFactory(
-		otlpHttpExporterFactory.Type(),
-		createDefaultConfig(otlpHttpExporterFactory),
-		exporter.WithMetrics(createMetrics(otlpHttpExporterFactory), otlpHttpExporterFactory.MetricsStability()),
+		metadata.Type,
+		createDefaultConfig,
+		exporter.WithMetrics(createMetrics, metadata.MetricsStability),
+		exporter.WithLogs(createLogs, metadata.LogsStability),
+		exporter.WithTraces(createTraces, metadata.TracesStability),
 	)
 }
-func createDefaultConfig(otlpHttpExporterFactory exporter.Factory) component.CreateDefaultConfigFunc {
-	return func() component.Config {
-		otlpHttpExporterDefaultConfig := otlpHttpExporterFactory.CreateDefaultConfig().(*otlphttpexporter.Config)
-		otlpHttpExporterDefaultConfig.Endpoint = ""
-		otlpHttpExporterDefaultConfig.Headers = map[string]configopaque.String{}
-		fmt.Println("Printing otlp default configs", otlpHttpExporterDefaultConfig.MetricsEndpoint)
-		return &Config{
-			OtlpHttpExporterConfig: otlpHttpExporterDefaultConfig,
-		}
+
+func createDefaultConfig() component.Config {
+	clientConfig := confighttp.NewDefaultClientConfig()
+	clientConfig.Timeout = 30 * time.Second
+	// Default to gzip compression
+	clientConfig.Compression = configcompression.TypeGzip
+	// We almost read 0 bytes, so no need to tune ReadBufferSize.
+	clientConfig.WriteBufferSize = 512 * 1024
+	clientConfig.Headers = map[string]configopaque.String{}
+
+	return &Config{
+		ClientConfig: clientConfig,
+		QueueConfig:  exporterhelper.NewDefaultQueueConfig(),
+		RetryConfig:  configretry.NewDefaultBackOffConfig(),
+		EventType:    defaultEventType,
+	}
+}
+
+func createMetrics(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Metrics, error) {
+	c := cfg.(*Config)
+	if err := c.validateEventsDestination("metrics", c.MetricsFormat, c.MetricsEndpoint); err != nil {
+		return nil, err
+	}
+	telemetryBuilder, err := metadata.NewTelemetryBuilder(set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+	oce, err := newExporter(pipeline.SignalMetrics, *c, set, telemetryBuilder)
+	if err != nil {
+		return nil, err
+	}
+	return exporterhelper.NewMetrics(ctx, set, c,
+		oce.pushMetrics,
+		exporterhelper.WithStart(oce.Start),
+		exporterhelper.WithShutdown(oce.Shutdown),
+		exporterhelper.WithCapabilities(consumer.Capabilities{MutatesData: false}),
+		// explicitly disable since we rely on http.Client timeout logic.
+		exporterhelper.WithTimeout(exporterhelper.TimeoutConfig{Timeout: 0}),
+		exporterhelper.WithRetry(c.RetryConfig),
+		exporterhelper.WithQueue(c.QueueConfig))
+}
+
+func createLogs(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Logs, error) {
+	c := cfg.(*Config)
+	if err := c.validateEventsDestination("logs", c.LogsFormat, c.LogsEndpoint); err != nil {
+		return nil, err
+	}
+	telemetryBuilder, err := metadata.NewTelemetryBuilder(set.TelemetrySettings)
+	if err != nil {
+		return nil, err
 	}
+	oce, err := newExporter(pipeline.SignalLogs, *c, set, telemetryBuilder)
+	if err != nil {
+		return nil, err
+	}
+	return exporterhelper.NewLogs(ctx, set, c,
+		oce.pushLogs,
+		exporterhelper.WithStart(oce.Start),
+		exporterhelper.WithShutdown(oce.Shutdown),
+		exporterhelper.WithCapabilities(consumer.Capabilities{MutatesData: false}),
+		// explicitly disable since we rely on http.Client timeout logic.
+		exporterhelper.WithTimeout(exporterhelper.TimeoutConfig{Timeout: 0}),
+		exporterhelper.WithRetry(c.RetryConfig),
+		exporterhelper.WithQueue(c.QueueConfig))
 }
-func createMetrics(otlpHttpExporterFactory exporter.Factory) exporter.CreateMetricsFunc {
-	return func(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Metrics, error) {
-		c := cfg.(*Config)
-		fmt.Println("create metrics config", c.OtlpHttpExporterConfig.MetricsEndpoint)
-		otlpExporter, err := otlpHttpExporterFactory.CreateMetrics(ctx, set, c.OtlpHttpExporterConfig)
-		if err != nil {
-			return nil, err
-		}
-		telemetryBuilder, err := metadata.NewTelemetryBuilder(set.TelemetrySettings)
-		if err != nil {
-			return nil, err
-		}
-		oce, err := newExporter(&otlpExporter, *c, set, telemetryBuilder)
-		if err != nil {
-			return nil, err
-		}
-		return exporterhelper.NewMetrics(ctx, set, c.OtlpHttpExporterConfig,
-			oce.pushMetrics,
-			exporterhelper.WithStart(oce.Start),
-			exporterhelper.WithCapabilities(otlpExporter.Capabilities()),
-			// explicitly disable since we rely on http.Client timeout logic.
-			exporterhelper.WithTimeout(exporterhelper.TimeoutConfig{Timeout: 0}),
-			exporterhelper.WithRetry(c.OtlpHttpExporterConfig.RetryConfig),
-			exporterhelper.WithQueue(c.OtlpHttpExporterConfig.QueueConfig))
+
+func createTraces(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Traces, error) {
+	c := cfg.(*Config)
+	if err := c.validateEventsDestination("traces", c.TracesFormat, c.TracesEndpoint); err != nil {
+		return nil, err
+	}
+	telemetryBuilder, err := metadata.NewTelemetryBuilder(set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+	oce, err := newExporter(pipeline.SignalTraces, *c, set, telemetryBuilder)
+	if err != nil {
+		return nil, err
 	}
+	return exporterhelper.NewTraces(ctx, set, c,
+		oce.pushTraces,
+		exporterhelper.WithStart(oce.Start),
+		exporterhelper.WithShutdown(oce.Shutdown),
+		exporterhelper.WithCapabilities(consumer.Capabilities{MutatesData: false}),
+		// explicitly disable since we rely on http.Client timeout logic.
+		exporterhelper.WithTimeout(exporterhelper.TimeoutConfig{Timeout: 0}),
+		exporterhelper.WithRetry(c.RetryConfig),
+		exporterhelper.WithQueue(c.QueueConfig))
 }
@@ -0,0 +1,110 @@
+package nreventexporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultFailoverRecoveryInterval is how long requests stay on a failover endpoint
+// when recovery_interval is not set.
+const defaultFailoverRecoveryInterval = 5 * time.Minute
+
+// FailoverConfig lists endpoints requests fall through to when the primary New Relic
+// endpoint can't be reached.
+type FailoverConfig struct {
+	// Endpoints are base URLs, such as an internal relay, tried in priority order when
+	// a request fails with a connection error or a 5xx response. The path of the
+	// request is appended to them.
+	Endpoints []string `mapstructure:"endpoints"`
+	// RecoveryInterval is how long requests stay on a failover endpoint before the
+	// primary is tried again. Defaults to 5m.
+	RecoveryInterval time.Duration `mapstructure:"recovery_interval"`
+}
+
+func (cfg *Config) validateFailover() error {
+	for _, endpoint := range cfg.Failover.Endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("failover::endpoints: %q is not a valid http or https URL", endpoint)
+		}
+	}
+	if cfg.Failover.RecoveryInterval < 0 {
+		return errors.New("failover::recovery_interval must not be negative")
+	}
+	return nil
+}
+
+// failoverURL sends the request for target to base instead, keeping its path and query.
+func failoverURL(base, target string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	t, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	b.Path = strings.TrimSuffix(b.Path, "/") + t.Path
+	b.RawQuery = t.RawQuery
+	return b.String(), nil
+}
+
+// send posts body to target. When failover endpoints are configured a connection
+// error or 5xx response falls through to the next endpoint, and the request and
+// response of the last attempt are returned.
+func (e *baseExporter) send(ctx context.Context, target string, body []byte, header http.Header, counter int) (*http.Request, *http.Response, error) {
+	order := []int{0}
+	if e.failover != nil {
+		order = e.failover.Order()
+	}
+
+	var (
+		req  *http.Request
+		resp *http.Response
+		err  error
+	)
+	for n, i := range order {
+		endpoint := target
+		if i > 0 {
+			if endpoint, err = failoverURL(e.config.Failover.Endpoints[i-1], target); err != nil {
+				return nil, nil, err
+			}
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Header = header.Clone()
+
+		start := time.Now()
+		resp, err = e.client.Do(req)
+		if err == nil {
+			e.recordMetrics(time.Since(start), counter, req, nil)
+		}
+		failed := err != nil || resp.StatusCode >= 500
+		if !failed {
+			if e.failover != nil {
+				e.failover.Success(i)
+			}
+			break
+		}
+		if n == len(order)-1 || ctx.Err() != nil {
+			break
+		}
+		if err == nil {
+			resp.Body.Close()
+			e.logger.Warn("Endpoint failed, trying the next one", zap.String("endpoint", endpoint), zap.Int("status_code", resp.StatusCode))
+		} else {
+			e.logger.Warn("Endpoint failed, trying the next one", zap.String("endpoint", endpoint), zap.Error(err))
+		}
+	}
+	return req, resp, err
+}
@@ -0,0 +1,51 @@
+package nreventexporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverURL(t *testing.T) {
+	got, err := failoverURL("https://relay.example.com:8443/nr/", "https://insights-collector.newrelic.com/v1/accounts/1/events?x=1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://relay.example.com:8443/nr/v1/accounts/1/events?x=1", got)
+}
+
+func TestExportFailsOverOnServerError(t *testing.T) {
+	var relayed atomic.Int32
+	relay := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/nr/v1/accounts/1/events", r.URL.Path)
+		relayed.Add(1)
+	}))
+	defer relay.Close()
+
+	e := newTestExporter(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}, func(cfg *Config) {
+		cfg.Failover.Endpoints = []string{"http://127.0.0.1:1", relay.URL + "/nr"}
+	})
+
+	require.NoError(t, e.export(context.Background(), e.config.Endpoint, []byte("[]"), e.nrAPIResponseHandler, 0, nil))
+	assert.Equal(t, int32(1), relayed.Load())
+}
+
+func TestExportDoesNotFailOverOnClientError(t *testing.T) {
+	relay := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		t.Error("request was sent to the failover endpoint")
+	}))
+	defer relay.Close()
+
+	e := newTestExporter(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}, func(cfg *Config) {
+		cfg.Failover.Endpoints = []string{relay.URL}
+	})
+
+	require.Error(t, e.export(context.Background(), e.config.Endpoint, []byte("[]"), e.nrAPIResponseHandler, 0, nil))
+}
@@ -0,0 +1,90 @@
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+)
+
+type state int
+
+const (
+	closed state = iota
+	open
+	halfOpen
+)
+
+// Breaker stops requests to an endpoint after a run of consecutive failures.
+// Once open it rejects requests for the cool-down period, then lets a single
+// probe request through: success closes the breaker, failure reopens it.
+type Breaker struct {
+	threshold int
+	coolDown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    state
+	failures int
+	openedAt time.Time
+}
+
+// New creates a Breaker that opens after threshold consecutive failures and stays
+// open for coolDown.
+func New(threshold int, coolDown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		coolDown:  coolDown,
+		now:       time.Now,
+	}
+}
+
+// Allow reports whether a request may be sent. When it may not, it returns how
+// long remains until the breaker lets a probe request through.
+func (b *Breaker) Allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case open:
+		if remaining := b.coolDown - b.now().Sub(b.openedAt); remaining > 0 {
+			return false, remaining
+		}
+		// Cool-down is over, let this request through as the probe.
+		b.state = halfOpen
+		return true, 0
+	case halfOpen:
+		// A probe is already in flight, wait for its outcome.
+		return false, b.coolDown
+	default:
+		return true, 0
+	}
+}
+
+// Success records a successful request, closing the breaker.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = closed
+	b.failures = 0
+}
+
+// Failure records a failed request, opening the breaker when the failed request
+// was the probe or the threshold of consecutive failures is reached.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == halfOpen || b.failures >= b.threshold {
+		b.state = open
+		b.openedAt = b.now()
+	}
+}
+
+// Release gives back the probe slot taken by Allow when the request was not sent
+// after all, so the next request becomes the probe instead.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == halfOpen {
+		// openedAt is unchanged, so the cool-down is already over.
+		b.state = open
+	}
+}
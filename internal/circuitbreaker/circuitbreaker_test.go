@@ -0,0 +1,96 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBreaker returns a Breaker whose clock is advanced by the returned function.
+func newTestBreaker(threshold int, coolDown time.Duration) (*Breaker, func(time.Duration)) {
+	b := New(threshold, coolDown)
+	now := time.Unix(0, 0)
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b, _ := newTestBreaker(3, time.Minute)
+	for i := 0; i < 2; i++ {
+		ok, _ := b.Allow()
+		require.True(t, ok)
+		b.Failure()
+	}
+	ok, _ := b.Allow()
+	require.True(t, ok)
+	b.Failure()
+
+	ok, wait := b.Allow()
+	assert.False(t, ok)
+	assert.Equal(t, time.Minute, wait)
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b, _ := newTestBreaker(2, time.Minute)
+	b.Failure()
+	b.Success()
+	b.Failure()
+	ok, _ := b.Allow()
+	assert.True(t, ok)
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	b, advance := newTestBreaker(1, time.Minute)
+	b.Failure()
+
+	advance(30 * time.Second)
+	ok, wait := b.Allow()
+	require.False(t, ok)
+	assert.Equal(t, 30*time.Second, wait)
+
+	advance(30 * time.Second)
+	ok, _ = b.Allow()
+	require.True(t, ok, "the first request after the cool-down is the probe")
+	ok, _ = b.Allow()
+	require.False(t, ok, "only one probe is let through")
+
+	b.Success()
+	ok, _ = b.Allow()
+	assert.True(t, ok)
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	b, advance := newTestBreaker(5, time.Minute)
+	for i := 0; i < 5; i++ {
+		b.Failure()
+	}
+	advance(time.Minute)
+	ok, _ := b.Allow()
+	require.True(t, ok)
+	b.Failure()
+
+	ok, wait := b.Allow()
+	assert.False(t, ok)
+	assert.Equal(t, time.Minute, wait)
+}
+
+func TestBreakerReleaseLetsNextRequestProbe(t *testing.T) {
+	b, advance := newTestBreaker(1, time.Minute)
+	b.Failure()
+	advance(time.Minute)
+	ok, _ := b.Allow()
+	require.True(t, ok)
+
+	b.Release()
+	ok, _ = b.Allow()
+	assert.True(t, ok)
+}
+
+func TestBreakerReleaseWhenClosed(t *testing.T) {
+	b, _ := newTestBreaker(1, time.Minute)
+	b.Release()
+	ok, _ := b.Allow()
+	assert.True(t, ok)
+}
@@ -0,0 +1,81 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+)
+
+const (
+	// Gzip compresses payloads with gzip, sent as Content-Encoding: gzip.
+	Gzip = "gzip"
+	// Deflate compresses payloads in the zlib format, sent as Content-Encoding: deflate.
+	Deflate = "deflate"
+	// None sends payloads uncompressed.
+	None = "none"
+)
+
+// Validate checks that the New Relic ingest APIs accept the compression method
+// and that level is valid for it. An empty method means None.
+func Validate(method string, level int) error {
+	switch method {
+	case Gzip, Deflate:
+		if level != gzip.DefaultCompression && level != gzip.HuffmanOnly &&
+			(level < gzip.NoCompression || level > gzip.BestCompression) {
+			return fmt.Errorf("invalid %s compression level %d", method, level)
+		}
+		return nil
+	case None, "":
+		return nil
+	default:
+		return fmt.Errorf("unsupported compression %q, must be one of %q, %q or %q", method, Gzip, Deflate, None)
+	}
+}
+
+// ContentEncoding returns the Content-Encoding header value for the method,
+// or "" when payloads are sent uncompressed.
+func ContentEncoding(method string) string {
+	switch method {
+	case Gzip, Deflate:
+		return method
+	default:
+		return ""
+	}
+}
+
+// Compress encodes payload with the compression method at the given level.
+// A level of 0 uses the default compression level.
+func Compress(method string, level int, payload []byte) ([]byte, error) {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	var buffer bytes.Buffer
+	switch method {
+	case Gzip:
+		gz, err := gzip.NewWriterLevel(&buffer, level)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := gz.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+	case Deflate:
+		zw, err := zlib.NewWriterLevel(&buffer, level)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return payload, nil
+	}
+	return buffer.Bytes(), nil
+}
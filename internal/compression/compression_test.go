@@ -0,0 +1,66 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decompress reverses Compress for method.
+func decompress(t *testing.T, method string, compressed []byte) []byte {
+	t.Helper()
+	var r io.ReadCloser
+	var err error
+	switch method {
+	case Gzip:
+		r, err = gzip.NewReader(bytes.NewReader(compressed))
+	case Deflate:
+		r, err = zlib.NewReader(bytes.NewReader(compressed))
+	}
+	require.NoError(t, err)
+	defer r.Close()
+	decompressed, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return decompressed
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"eventType":"OtelEvent","value":1}`), 100)
+	for _, method := range []string{Gzip, Deflate} {
+		for _, level := range []int{0, gzip.BestSpeed, gzip.BestCompression} {
+			compressed, err := Compress(method, level, payload)
+			require.NoError(t, err)
+			assert.Less(t, len(compressed), len(payload))
+			assert.Equal(t, payload, decompress(t, method, compressed), "%s level %d", method, level)
+		}
+	}
+}
+
+func TestCompressNone(t *testing.T) {
+	payload := []byte("[]")
+	for _, method := range []string{None, ""} {
+		compressed, err := Compress(method, 0, payload)
+		require.NoError(t, err)
+		assert.Equal(t, payload, compressed)
+	}
+}
+
+func TestContentEncoding(t *testing.T) {
+	assert.Equal(t, "gzip", ContentEncoding(Gzip))
+	assert.Equal(t, "deflate", ContentEncoding(Deflate))
+	assert.Empty(t, ContentEncoding(None))
+	assert.Empty(t, ContentEncoding(""))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(Gzip, 0))
+	assert.NoError(t, Validate(Deflate, gzip.BestCompression))
+	assert.NoError(t, Validate(None, 0))
+	assert.EqualError(t, Validate(Gzip, 42), "invalid gzip compression level 42")
+	assert.EqualError(t, Validate("zstd", 0), `unsupported compression "zstd", must be one of "gzip", "deflate" or "none"`)
+}
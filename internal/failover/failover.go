@@ -0,0 +1,52 @@
+package failover
+
+import (
+	"sync"
+	"time"
+)
+
+// Selector picks the order endpoints are tried in. Endpoint 0 is the primary and
+// the rest are listed by priority. Requests stick to the endpoint that last
+// succeeded until the recovery interval has passed, then the primary is tried again.
+type Selector struct {
+	n        int
+	recovery time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	active int
+	since  time.Time
+}
+
+// New creates a Selector over n endpoints.
+func New(n int, recovery time.Duration) *Selector {
+	return &Selector{n: n, recovery: recovery, now: time.Now}
+}
+
+// Order returns the endpoint indexes to try: the active endpoint first, then the
+// others by priority.
+func (s *Selector) Order() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != 0 && s.now().Sub(s.since) >= s.recovery {
+		s.active = 0
+	}
+	order := make([]int, 0, s.n)
+	order = append(order, s.active)
+	for i := 0; i < s.n; i++ {
+		if i != s.active {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// Success records that endpoint i accepted a request, making it the active endpoint.
+func (s *Selector) Success(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i != s.active {
+		s.active = i
+		s.since = s.now()
+	}
+}
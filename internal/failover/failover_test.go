@@ -0,0 +1,49 @@
+package failover
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSelector returns a Selector whose clock is advanced by the returned function.
+func newTestSelector(n int, recovery time.Duration) (*Selector, func(time.Duration)) {
+	s := New(n, recovery)
+	now := time.Unix(0, 0)
+	s.now = func() time.Time { return now }
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestOrderStartsWithPrimary(t *testing.T) {
+	s, _ := newTestSelector(3, time.Minute)
+	assert.Equal(t, []int{0, 1, 2}, s.Order())
+}
+
+func TestOrderSticksToEndpointThatSucceeded(t *testing.T) {
+	s, advance := newTestSelector(3, time.Minute)
+	s.Success(2)
+	assert.Equal(t, []int{2, 0, 1}, s.Order())
+
+	advance(59 * time.Second)
+	s.Success(2)
+	assert.Equal(t, []int{2, 0, 1}, s.Order(), "staying on the endpoint doesn't restart the recovery interval")
+}
+
+func TestOrderReturnsToPrimaryAfterRecoveryInterval(t *testing.T) {
+	s, advance := newTestSelector(3, time.Minute)
+	s.Success(1)
+	advance(time.Minute)
+	assert.Equal(t, []int{0, 1, 2}, s.Order())
+
+	// The primary is still down, so requests fail over again.
+	s.Success(1)
+	advance(30 * time.Second)
+	assert.Equal(t, []int{1, 0, 2}, s.Order())
+}
+
+func TestSuccessOnPrimaryKeepsPrimary(t *testing.T) {
+	s, _ := newTestSelector(2, time.Minute)
+	s.Success(0)
+	assert.Equal(t, []int{0, 1}, s.Order())
+}
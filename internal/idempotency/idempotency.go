@@ -0,0 +1,48 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// EventIDAttribute is the event attribute holding the event's ID.
+const EventIDAttribute = "otel.event.id"
+
+// idLength is the number of hash bytes kept in an ID.
+const idLength = 16
+
+// RequestID returns an ID for an uncompressed request payload. A batch that is
+// retried converts to the same payload, so it is sent with the same ID.
+func RequestID(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:idLength])
+}
+
+// AddEventIDs sets EventIDAttribute on every event to a hash of its attributes,
+// which identify the stream it belongs to, and its timestamp. The same data point,
+// log record or span always gets the same ID, so duplicates can be dropped with
+// uniques(otel.event.id).
+func AddEventIDs[E ~map[string]interface{}](events []E) {
+	for _, event := range events {
+		event[EventIDAttribute] = eventID(event)
+	}
+}
+
+func eventID[E ~map[string]interface{}](event E) string {
+	keys := make([]string, 0, len(event))
+	for k := range event {
+		if k != EventIDAttribute {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		// NUL separators keep "a"+"bc" and "ab"+"c" apart.
+		fmt.Fprintf(h, "%s\x00%v\x00", k, event[k])
+	}
+	return hex.EncodeToString(h.Sum(nil)[:idLength])
+}
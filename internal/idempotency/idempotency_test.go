@@ -0,0 +1,73 @@
+package idempotency_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jwang25/nreventexporter/internal/idempotency"
+	"github.com/jwang25/nreventexporter/internal/logtoevent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+func testLogs(ts time.Time) plog.Logs {
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("service.name", "checkout")
+	records := rl.ScopeLogs().AppendEmpty().LogRecords()
+	for _, msg := range []string{"first", "second"} {
+		lr := records.AppendEmpty()
+		lr.Body().SetStr(msg)
+		lr.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+		lr.Attributes().PutStr("user.id", "42")
+	}
+	return ld
+}
+
+// convert turns ld into events with IDs and the request payload sent for them.
+func convert(t *testing.T, ld plog.Logs) ([]string, []byte) {
+	t.Helper()
+	events := logtoevent.LogsToNREvents(zap.NewNop(), ld, "OtelEvent")
+	idempotency.AddEventIDs(events)
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event[idempotency.EventIDAttribute].(string))
+	}
+	payload, err := json.Marshal(events)
+	require.NoError(t, err)
+	return ids, payload
+}
+
+func TestIDsAreStableAcrossConversions(t *testing.T) {
+	ts := time.UnixMilli(1_700_000_000_000)
+	ids, payload := convert(t, testLogs(ts))
+	idsAgain, payloadAgain := convert(t, testLogs(ts))
+
+	assert.Equal(t, ids, idsAgain)
+	assert.NotEqual(t, ids[0], ids[1], "different records get different IDs")
+	assert.Equal(t, idempotency.RequestID(payload), idempotency.RequestID(payloadAgain))
+	assert.Len(t, idempotency.RequestID(payload), 32)
+}
+
+func TestIDsChangeWithTimestamp(t *testing.T) {
+	ts := time.UnixMilli(1_700_000_000_000)
+	ids, payload := convert(t, testLogs(ts))
+	laterIDs, laterPayload := convert(t, testLogs(ts.Add(time.Millisecond)))
+
+	assert.NotEqual(t, ids[0], laterIDs[0])
+	assert.NotEqual(t, ids[1], laterIDs[1])
+	assert.NotEqual(t, idempotency.RequestID(payload), idempotency.RequestID(laterPayload))
+}
+
+func TestAddEventIDsIgnoresExistingID(t *testing.T) {
+	event := map[string]interface{}{"eventType": "OtelEvent", "timestamp": int64(1)}
+	idempotency.AddEventIDs([]map[string]interface{}{event})
+	id := event[idempotency.EventIDAttribute]
+
+	idempotency.AddEventIDs([]map[string]interface{}{event})
+	assert.Equal(t, id, event[idempotency.EventIDAttribute], "an event's ID doesn't hash its previous ID")
+}
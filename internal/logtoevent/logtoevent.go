@@ -0,0 +1,69 @@
+package logtoevent
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+type nrEvent map[string]interface{}
+
+// logRecordToEventMap will return a single event built from the log record,
+// its resource attributes and its own attributes. Log attributes are applied
+// last so an eventType attribute on the record routes it to that event type.
+func logRecordToEventMap(eventType string, res pcommon.Resource, lr plog.LogRecord) nrEvent {
+	nrEventMap := make(nrEvent)
+	nrEventMap["eventType"] = eventType
+
+	res.Attributes().Range(func(k string, val pcommon.Value) bool {
+		nrEventMap[k] = val.AsString()
+		return true
+	})
+
+	nrEventMap["message"] = lr.Body().AsString()
+	if lr.SeverityText() != "" {
+		nrEventMap["severity.text"] = lr.SeverityText()
+	}
+	if lr.SeverityNumber() != plog.SeverityNumberUnspecified {
+		nrEventMap["severity.number"] = int32(lr.SeverityNumber())
+	}
+	if !lr.TraceID().IsEmpty() {
+		nrEventMap["trace.id"] = lr.TraceID().String()
+	}
+	if !lr.SpanID().IsEmpty() {
+		nrEventMap["span.id"] = lr.SpanID().String()
+	}
+
+	// The Event API expects the timestamp as milliseconds since the epoch.
+	ts := lr.Timestamp()
+	if ts == 0 {
+		ts = lr.ObservedTimestamp()
+	}
+	if ts != 0 {
+		nrEventMap["timestamp"] = ts.AsTime().UnixMilli()
+	}
+
+	lr.Attributes().Range(func(k string, val pcommon.Value) bool {
+		nrEventMap[k] = val.AsString()
+		return true
+	})
+	return nrEventMap
+}
+
+// LogsToNREvents converts plog.Logs to New Relic events, one per log record.
+func LogsToNREvents(logger *zap.Logger, ld plog.Logs, eventType string) []nrEvent {
+	var nrEventList []nrEvent
+	rls := ld.ResourceLogs()
+	logger.Debug("LogsExporter", zap.Int("ResourceLogsCount", rls.Len()))
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				nrEventList = append(nrEventList, logRecordToEventMap(eventType, rl.Resource(), sl.LogRecords().At(k)))
+			}
+		}
+	}
+
+	return nrEventList
+}
@@ -0,0 +1,101 @@
+package logtonrlog
+
+import (
+	"encoding/json"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+// nrLogBlock is one element of a New Relic Log API payload. Attributes shared by
+// every log in the block go in common, see
+// https://docs.newrelic.com/docs/logs/log-api/introduction-log-api/#json-content
+type nrLogBlock struct {
+	Common nrCommon `json:"common"`
+	Logs   []nrLog  `json:"logs"`
+}
+
+type nrCommon struct {
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type nrLog struct {
+	Timestamp  int64                  `json:"timestamp,omitempty"`
+	Message    string                 `json:"message"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// logRecordToNRLog will return a Log API entry for the log record. Severity and
+// trace context are carried as the attributes New Relic uses for them in the UI.
+func logRecordToNRLog(lr plog.LogRecord) nrLog {
+	attrs := make(map[string]interface{})
+	if lr.SeverityText() != "" {
+		attrs["level"] = lr.SeverityText()
+	}
+	if lr.SeverityNumber() != plog.SeverityNumberUnspecified {
+		attrs["severity.number"] = int32(lr.SeverityNumber())
+	}
+	if !lr.TraceID().IsEmpty() {
+		attrs["trace.id"] = lr.TraceID().String()
+	}
+	if !lr.SpanID().IsEmpty() {
+		attrs["span.id"] = lr.SpanID().String()
+	}
+	lr.Attributes().Range(func(k string, val pcommon.Value) bool {
+		attrs[k] = val.AsString()
+		return true
+	})
+
+	ts := lr.Timestamp()
+	if ts == 0 {
+		ts = lr.ObservedTimestamp()
+	}
+	var timestamp int64
+	if ts != 0 {
+		timestamp = ts.AsTime().UnixMilli()
+	}
+
+	return nrLog{
+		Timestamp:  timestamp,
+		Message:    lr.Body().AsString(),
+		Attributes: attrs,
+	}
+}
+
+// LogsToNRLogs converts plog.Logs to New Relic Log API blocks, one per resource
+// with the resource attributes in the common block.
+func LogsToNRLogs(logger *zap.Logger, ld plog.Logs) []nrLogBlock {
+	var blocks []nrLogBlock
+	rls := ld.ResourceLogs()
+	logger.Debug("LogsExporter", zap.Int("ResourceLogsCount", rls.Len()))
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		block := nrLogBlock{Common: nrCommon{Attributes: make(map[string]interface{})}}
+		rl.Resource().Attributes().Range(func(k string, val pcommon.Value) bool {
+			block.Common.Attributes[k] = val.AsString()
+			return true
+		})
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				block.Logs = append(block.Logs, logRecordToNRLog(sl.LogRecords().At(k)))
+			}
+		}
+		if len(block.Logs) > 0 {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// Build the Log API JSON payload from plog.Logs
+func BuildNRLogPayload(logger *zap.Logger, ld plog.Logs) ([]byte, int) {
+	blocks := LogsToNRLogs(logger, ld)
+	counter := 0
+	for _, block := range blocks {
+		counter += len(block.Logs)
+	}
+	request, _ := json.Marshal(blocks)
+	return request, counter
+}
@@ -0,0 +1,57 @@
+package logtonrlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+func TestBuildNRLogPayload(t *testing.T) {
+	ts := time.UnixMilli(1_700_000_000_123)
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("service.name", "checkout")
+	records := rl.ScopeLogs().AppendEmpty().LogRecords()
+
+	lr := records.AppendEmpty()
+	lr.Body().SetStr("payment failed")
+	lr.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+	lr.SetSeverityText("ERROR")
+	lr.SetSeverityNumber(plog.SeverityNumberError)
+	lr.SetTraceID(pcommon.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	lr.SetSpanID(pcommon.SpanID{1, 2, 3, 4, 5, 6, 7, 8})
+	lr.Attributes().PutInt("attempt", 2)
+
+	// Without a timestamp the observed timestamp is used.
+	lr = records.AppendEmpty()
+	lr.Body().SetStr("retrying")
+	lr.SetObservedTimestamp(pcommon.NewTimestampFromTime(ts.Add(time.Second)))
+
+	// Resources without logs don't produce an empty block.
+	ld.ResourceLogs().AppendEmpty().Resource().Attributes().PutStr("service.name", "idle")
+
+	request, counter := BuildNRLogPayload(zap.NewNop(), ld)
+	assert.Equal(t, 2, counter)
+	require.JSONEq(t, `[{
+		"common": {"attributes": {"service.name": "checkout"}},
+		"logs": [
+			{
+				"timestamp": 1700000000123,
+				"message": "payment failed",
+				"attributes": {
+					"level": "ERROR",
+					"severity.number": 17,
+					"trace.id": "0102030405060708090a0b0c0d0e0f10",
+					"span.id": "0102030405060708",
+					"attempt": "2"
+				}
+			},
+			{"timestamp": 1700000001123, "message": "retrying"}
+		]
+	}]`, string(request))
+}
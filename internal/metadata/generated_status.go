@@ -12,5 +12,7 @@ var (
 )
 
 const (
+	TracesStability  = component.StabilityLevelAlpha
 	MetricsStability = component.StabilityLevelBeta
+	LogsStability    = component.StabilityLevelAlpha
 )
@@ -23,13 +23,14 @@ func Tracer(settings component.TelemetrySettings) trace.Tracer {
 // TelemetryBuilder provides an interface for components to report telemetry
 // as defined in metadata and user config.
 type TelemetryBuilder struct {
-	meter                    metric.Meter
-	mu                       sync.Mutex
-	registrations            []metric.Registration
-	ExporterRequestsBytes    metric.Int64Counter
-	ExporterRequestsDuration metric.Int64Counter
-	ExporterRequestsRecords  metric.Int64Counter
-	ExporterRequestsSent     metric.Int64Counter
+	meter                         metric.Meter
+	mu                            sync.Mutex
+	registrations                 []metric.Registration
+	ExporterRateLimitWaitDuration metric.Int64Counter
+	ExporterRequestsBytes         metric.Int64Counter
+	ExporterRequestsDuration      metric.Int64Counter
+	ExporterRequestsRecords       metric.Int64Counter
+	ExporterRequestsSent          metric.Int64Counter
 }
 
 // TelemetryBuilderOption applies changes to default builder.
@@ -61,6 +62,12 @@ func NewTelemetryBuilder(settings component.TelemetrySettings, options ...Teleme
 	}
 	builder.meter = Meter(settings)
 	var err, errs error
+	builder.ExporterRateLimitWaitDuration, err = builder.meter.Int64Counter(
+		"otelcol_exporter_rate_limit_wait_duration",
+		metric.WithDescription("Time requests spent waiting for the client-side rate limiter (in milliseconds)"),
+		metric.WithUnit("ms"),
+	)
+	errs = errors.Join(errs, err)
 	builder.ExporterRequestsBytes, err = builder.meter.Int64Counter(
 		"otelcol_exporter_requests_bytes",
 		metric.WithDescription("Total size of requests (in bytes)"),
@@ -18,10 +18,14 @@ func TestSetupTelemetry(t *testing.T) {
 	tb, err := metadata.NewTelemetryBuilder(testTel.NewTelemetrySettings())
 	require.NoError(t, err)
 	defer tb.Shutdown()
+	tb.ExporterRateLimitWaitDuration.Add(context.Background(), 1)
 	tb.ExporterRequestsBytes.Add(context.Background(), 1)
 	tb.ExporterRequestsDuration.Add(context.Background(), 1)
 	tb.ExporterRequestsRecords.Add(context.Background(), 1)
 	tb.ExporterRequestsSent.Add(context.Background(), 1)
+	AssertEqualExporterRateLimitWaitDuration(t, testTel,
+		[]metricdata.DataPoint[int64]{{Value: 1}},
+		metricdatatest.IgnoreTimestamp())
 	AssertEqualExporterRequestsBytes(t, testTel,
 		[]metricdata.DataPoint[int64]{{Value: 1}},
 		metricdatatest.IgnoreTimestamp())
@@ -0,0 +1,150 @@
+package metricfilter
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// MatchType describes how metric name patterns are interpreted.
+type MatchType string
+
+const (
+	// Strict matches metric names exactly.
+	Strict MatchType = "strict"
+	// Glob matches metric names using shell style patterns (see path.Match).
+	Glob MatchType = "glob"
+	// Regexp matches metric names using RE2 regular expressions.
+	Regexp MatchType = "regexp"
+)
+
+// Matcher reports whether a metric name matches one of its patterns.
+type Matcher interface {
+	Matches(name string) bool
+}
+
+type strictMatcher map[string]struct{}
+
+func (m strictMatcher) Matches(name string) bool {
+	_, ok := m[name]
+	return ok
+}
+
+type globMatcher []string
+
+func (m globMatcher) Matches(name string) bool {
+	for _, pattern := range m {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type regexpMatcher []*regexp.Regexp
+
+func (m regexpMatcher) Matches(name string) bool {
+	for _, re := range m {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewMatcher builds a Matcher for the given patterns. An empty match type defaults to Strict.
+func NewMatcher(matchType MatchType, patterns []string) (Matcher, error) {
+	switch matchType {
+	case Strict, "":
+		m := make(strictMatcher, len(patterns))
+		for _, p := range patterns {
+			m[p] = struct{}{}
+		}
+		return m, nil
+	case Glob:
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob pattern %q: %w", p, err)
+			}
+		}
+		return globMatcher(patterns), nil
+	case Regexp:
+		m := make(regexpMatcher, 0, len(patterns))
+		for _, p := range patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regexp pattern %q: %w", p, err)
+			}
+			m = append(m, re)
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unknown match type %q", matchType)
+	}
+}
+
+// Filter decides which metrics are kept based on include and exclude matchers.
+// A nil include matcher keeps every metric not explicitly excluded.
+type Filter struct {
+	include Matcher
+	exclude Matcher
+}
+
+// NewFilter creates a Filter from the include and exclude matchers, either of which may be nil.
+func NewFilter(include, exclude Matcher) *Filter {
+	return &Filter{include: include, exclude: exclude}
+}
+
+// Keep reports whether a metric with the given name passes the filter.
+func (f *Filter) Keep(name string) bool {
+	if f.include != nil && !f.include.Matches(name) {
+		return false
+	}
+	if f.exclude != nil && f.exclude.Matches(name) {
+		return false
+	}
+	return true
+}
+
+// FilterMetrics returns a copy of md holding only the metrics that pass the filter.
+// Resources and scopes left without metrics are dropped. md itself is not modified
+// since it may be shared with other exporters in the pipeline.
+func (f *Filter) FilterMetrics(md pmetric.Metrics) pmetric.Metrics {
+	if f == nil || (f.include == nil && f.exclude == nil) {
+		return md
+	}
+	out := pmetric.NewMetrics()
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		var outRm pmetric.ResourceMetrics
+		hasRm := false
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			var outSm pmetric.ScopeMetrics
+			hasSm := false
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				m := sm.Metrics().At(k)
+				if !f.Keep(m.Name()) {
+					continue
+				}
+				if !hasRm {
+					outRm = out.ResourceMetrics().AppendEmpty()
+					hasRm = true
+					rm.Resource().CopyTo(outRm.Resource())
+					outRm.SetSchemaUrl(rm.SchemaUrl())
+				}
+				if !hasSm {
+					outSm = outRm.ScopeMetrics().AppendEmpty()
+					hasSm = true
+					sm.Scope().CopyTo(outSm.Scope())
+					outSm.SetSchemaUrl(sm.SchemaUrl())
+				}
+				m.CopyTo(outSm.Metrics().AppendEmpty())
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,131 @@
+package metricfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestNewMatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		matchType MatchType
+		patterns  []string
+		matches   []string
+		misses    []string
+	}{
+		{
+			name:     "default is strict",
+			patterns: []string{"http.requests"},
+			matches:  []string{"http.requests"},
+			misses:   []string{"http.requests.total", "http.*"},
+		},
+		{
+			name:      "strict",
+			matchType: Strict,
+			patterns:  []string{"a", "b"},
+			matches:   []string{"a", "b"},
+			misses:    []string{"ab", ""},
+		},
+		{
+			name:      "glob",
+			matchType: Glob,
+			patterns:  []string{"http.*", "db.?"},
+			matches:   []string{"http.requests", "db.a"},
+			misses:    []string{"rpc.calls", "db.ab"},
+		},
+		{
+			name:      "regexp",
+			matchType: Regexp,
+			patterns:  []string{`^system\.(cpu|memory)\.`},
+			matches:   []string{"system.cpu.time", "system.memory.usage"},
+			misses:    []string{"process.system.cpu.time", "system.disk.io"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMatcher(tt.matchType, tt.patterns)
+			require.NoError(t, err)
+			for _, name := range tt.matches {
+				assert.True(t, m.Matches(name), name)
+			}
+			for _, name := range tt.misses {
+				assert.False(t, m.Matches(name), name)
+			}
+		})
+	}
+}
+
+func TestNewMatcherRejectsInvalidPatterns(t *testing.T) {
+	_, err := NewMatcher(Glob, []string{"http.["})
+	assert.ErrorContains(t, err, `invalid glob pattern "http.["`)
+
+	_, err = NewMatcher(Regexp, []string{"http.("})
+	assert.ErrorContains(t, err, `invalid regexp pattern "http.("`)
+
+	_, err = NewMatcher("fuzzy", nil)
+	assert.EqualError(t, err, `unknown match type "fuzzy"`)
+}
+
+// testMetrics returns two resources: one with a scope holding a and b and a scope
+// holding c, and one with a scope holding only c.
+func testMetrics() pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("service.name", "first")
+	sm := rm.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName("scope1")
+	sm.Metrics().AppendEmpty().SetName("a")
+	sm.Metrics().AppendEmpty().SetName("b")
+	sm = rm.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName("scope2")
+	sm.Metrics().AppendEmpty().SetName("c")
+
+	rm = md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("service.name", "second")
+	rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetName("c")
+	return md
+}
+
+func TestFilterMetricsIncludeThenExclude(t *testing.T) {
+	include, err := NewMatcher(Glob, []string{"*"})
+	require.NoError(t, err)
+	exclude, err := NewMatcher(Strict, []string{"b", "c"})
+	require.NoError(t, err)
+	md := testMetrics()
+
+	out := NewFilter(include, exclude).FilterMetrics(md)
+
+	// Only a is left, so the second resource and the first resource's second
+	// scope are dropped rather than sent empty.
+	require.Equal(t, 1, out.ResourceMetrics().Len())
+	rm := out.ResourceMetrics().At(0)
+	name, _ := rm.Resource().Attributes().Get("service.name")
+	assert.Equal(t, "first", name.Str())
+	require.Equal(t, 1, rm.ScopeMetrics().Len())
+	sm := rm.ScopeMetrics().At(0)
+	assert.Equal(t, "scope1", sm.Scope().Name())
+	require.Equal(t, 1, sm.Metrics().Len())
+	assert.Equal(t, "a", sm.Metrics().At(0).Name())
+
+	assert.Equal(t, 4, md.MetricCount(), "the input is not modified")
+}
+
+func TestFilterMetricsExcludeWins(t *testing.T) {
+	include, err := NewMatcher(Strict, []string{"a", "c"})
+	require.NoError(t, err)
+	exclude, err := NewMatcher(Strict, []string{"a"})
+	require.NoError(t, err)
+
+	out := NewFilter(include, exclude).FilterMetrics(testMetrics())
+	assert.Equal(t, 2, out.MetricCount())
+	assert.Equal(t, 2, out.ResourceMetrics().Len())
+	assert.Equal(t, 1, out.ResourceMetrics().At(0).ScopeMetrics().Len())
+}
+
+func TestFilterMetricsWithoutMatchersKeepsEverything(t *testing.T) {
+	md := testMetrics()
+	assert.Equal(t, md, NewFilter(nil, nil).FilterMetrics(md))
+}
@@ -1,8 +1,6 @@
 package metrictoevent
 
 import (
-	"bytes"
-	"compress/gzip"
 	"encoding/json"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
@@ -26,8 +24,9 @@ func resourceToEventMap(res pcommon.Resource, eventMap nrEvent) error {
 }
 
 // metricToEventMap will return a set of dimensions from the
-// metric attributes.
-func metricToEventMap(eventType string, currentMetric pmetric.Metric) nrEvent {
+// metric attributes. When normalizeUnits is set the value is converted
+// according to the metric's Unit() and the resulting unit is recorded.
+func metricToEventMap(eventType string, currentMetric pmetric.Metric, normalizeUnits bool) nrEvent {
 	nrEventMap := make(nrEvent)
 	nrEventMap["eventType"] = eventType
 	nrEventMap["name"] = currentMetric.Name()
@@ -61,11 +60,14 @@ func metricToEventMap(eventType string, currentMetric pmetric.Metric) nrEvent {
 	} else {
 		// We need to handle Histogram and Summary most likely
 	}
+	if normalizeUnits {
+		normalizeUnit(nrEventMap, currentMetric.Unit())
+	}
 	return nrEventMap
 }
 
 // MetricsToNREvents converts pdata.Metrics to New Relic event json.
-func MetricsToNREvents(logger *zap.Logger, md pmetric.Metrics, eventType string) []nrEvent {
+func MetricsToNREvents(logger *zap.Logger, md pmetric.Metrics, eventType string, normalizeUnits bool) []nrEvent {
 	var nrEventList []nrEvent
 	rms := md.ResourceMetrics()
 	logger.Debug("MetricsExporter", zap.Int("ResourceMetricsCount", rms.Len()))
@@ -77,7 +79,7 @@ func MetricsToNREvents(logger *zap.Logger, md pmetric.Metrics, eventType string)
 			ilm := rm.ScopeMetrics().At(j)
 			for k := 0; k < ilm.Metrics().Len(); k++ {
 				currentMetric := ilm.Metrics().At(k)
-				nrEventMap := metricToEventMap(eventType, currentMetric)
+				nrEventMap := metricToEventMap(eventType, currentMetric, normalizeUnits)
 				nrEventList = append(nrEventList, nrEventMap)
 			}
 		}
@@ -86,21 +88,10 @@ func MetricsToNREvents(logger *zap.Logger, md pmetric.Metrics, eventType string)
 	return nrEventList
 }
 
-// Build the compressed JSON payload from pdata.Metrics
-func BuildNREventPayload(logger *zap.Logger, md pmetric.Metrics, eventType string) ([]byte, int) {
-	nrEventList := MetricsToNREvents(logger, md, eventType)
+// Build the JSON payload from pdata.Metrics
+func BuildNREventPayload(logger *zap.Logger, md pmetric.Metrics, eventType string, normalizeUnits bool) ([]byte, int) {
+	nrEventList := MetricsToNREvents(logger, md, eventType, normalizeUnits)
 	// Convert the slice of maps to a JSON string
 	request, _ := json.Marshal(nrEventList)
-
-	// we gzip this and return it
-	return CompressNREventPayload(string(request)), len(nrEventList)
-}
-
-func CompressNREventPayload(payload string) []byte {
-	// Compress the JSON string
-	var buffer bytes.Buffer
-	gz := gzip.NewWriter(&buffer)
-	gz.Write([]byte(payload))
-	gz.Close()
-	return buffer.Bytes()
+	return request, len(nrEventList)
 }
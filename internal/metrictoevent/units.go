@@ -0,0 +1,62 @@
+package metrictoevent
+
+// unitConversion describes how to bring a value expressed in a UCUM unit
+// to its canonical unit.
+type unitConversion struct {
+	unit   string
+	factor float64
+}
+
+// unitConversions maps the UCUM units commonly emitted by SDKs and receivers
+// to seconds, bytes or a dimensionless ratio.
+var unitConversions = map[string]unitConversion{
+	// durations
+	"ns":  {unit: "s", factor: 1e-9},
+	"us":  {unit: "s", factor: 1e-6},
+	"μs":  {unit: "s", factor: 1e-6},
+	"ms":  {unit: "s", factor: 1e-3},
+	"s":   {unit: "s", factor: 1},
+	"min": {unit: "s", factor: 60},
+	"h":   {unit: "s", factor: 3600},
+	"d":   {unit: "s", factor: 86400},
+	// bytes
+	"By":   {unit: "By", factor: 1},
+	"kBy":  {unit: "By", factor: 1e3},
+	"MBy":  {unit: "By", factor: 1e6},
+	"GBy":  {unit: "By", factor: 1e9},
+	"TBy":  {unit: "By", factor: 1e12},
+	"KiBy": {unit: "By", factor: 1 << 10},
+	"MiBy": {unit: "By", factor: 1 << 20},
+	"GiBy": {unit: "By", factor: 1 << 30},
+	"TiBy": {unit: "By", factor: 1 << 40},
+	"bit":  {unit: "By", factor: 1.0 / 8},
+	// ratios
+	"%": {unit: "1", factor: 0.01},
+	"1": {unit: "1", factor: 1},
+}
+
+// normalizeUnit converts the value and valueType already stored on the event to
+// the canonical unit for the metric's Unit() and records that unit on the event.
+// Units without a known conversion are recorded unchanged and the value is left as is.
+func normalizeUnit(nrEventMap nrEvent, unit string) {
+	if unit == "" {
+		return
+	}
+	conv, ok := unitConversions[unit]
+	if !ok {
+		nrEventMap["unit"] = unit
+		return
+	}
+	nrEventMap["unit"] = conv.unit
+	if conv.factor == 1 {
+		return
+	}
+	switch v := nrEventMap["value"].(type) {
+	case float64:
+		nrEventMap["value"] = v * conv.factor
+	case int64:
+		nrEventMap["value"] = float64(v) * conv.factor
+		nrEventMap["valueType"] = "Double"
+	}
+	nrEventMap["originalUnit"] = unit
+}
@@ -0,0 +1,58 @@
+package metrictoevent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeUnit(t *testing.T) {
+	tests := []struct {
+		name  string
+		event nrEvent
+		unit  string
+		want  nrEvent
+	}{
+		{
+			name:  "int converted to Double",
+			event: nrEvent{"value": int64(1500), "valueType": "Int"},
+			unit:  "ms",
+			want:  nrEvent{"value": 1.5, "valueType": "Double", "unit": "s", "originalUnit": "ms"},
+		},
+		{
+			name:  "double",
+			event: nrEvent{"value": 2.0, "valueType": "Double"},
+			unit:  "kBy",
+			want:  nrEvent{"value": 2000.0, "valueType": "Double", "unit": "By", "originalUnit": "kBy"},
+		},
+		{
+			name:  "percent to ratio",
+			event: nrEvent{"value": 50.0, "valueType": "Double"},
+			unit:  "%",
+			want:  nrEvent{"value": 0.5, "valueType": "Double", "unit": "1", "originalUnit": "%"},
+		},
+		{
+			name:  "canonical unit",
+			event: nrEvent{"value": int64(3), "valueType": "Int"},
+			unit:  "By",
+			want:  nrEvent{"value": int64(3), "valueType": "Int", "unit": "By"},
+		},
+		{
+			name:  "unknown unit",
+			event: nrEvent{"value": int64(3), "valueType": "Int"},
+			unit:  "{requests}",
+			want:  nrEvent{"value": int64(3), "valueType": "Int", "unit": "{requests}"},
+		},
+		{
+			name:  "no unit",
+			event: nrEvent{"value": int64(3), "valueType": "Int"},
+			want:  nrEvent{"value": int64(3), "valueType": "Int"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalizeUnit(tt.event, tt.unit)
+			assert.Equal(t, tt.want, tt.event)
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package metrictonrmetric
+
+import (
+	"encoding/json"
+	"math"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+// nrMetricBlock is one element of a New Relic Metric API payload. Attributes shared
+// by every metric in the block go in common, see
+// https://docs.newrelic.com/docs/data-apis/ingest-apis/metric-api/report-metrics-metric-api/
+type nrMetricBlock struct {
+	Common  nrCommon   `json:"common"`
+	Metrics []nrMetric `json:"metrics"`
+}
+
+type nrCommon struct {
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type nrMetric map[string]interface{}
+
+type nrSummaryValue struct {
+	Count float64  `json:"count"`
+	Sum   float64  `json:"sum"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+func attributesToMap(attrs pcommon.Map) map[string]interface{} {
+	out := make(map[string]interface{}, attrs.Len())
+	attrs.Range(func(k string, val pcommon.Value) bool {
+		out[k] = val.AsString()
+		return true
+	})
+	return out
+}
+
+// isFinite reports whether v can be encoded as JSON. NaN and infinities are valid
+// OTel values, reported by Prometheus summaries for example, but not JSON numbers.
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// hasInterval reports whether a data point covers a time interval, which the Metric
+// API requires for counts and summaries.
+func hasInterval(start, end pcommon.Timestamp) bool {
+	return start != 0 && end > start
+}
+
+// finiteNumber reports whether a number data point has a value that can be sent.
+func finiteNumber(dp pmetric.NumberDataPoint) bool {
+	return dp.ValueType() != pmetric.NumberDataPointValueTypeDouble || isFinite(dp.DoubleValue())
+}
+
+// setMinMax sets the summary's min and max when they are known and finite.
+func (v *nrSummaryValue) setMinMax(hasMin bool, minValue float64, hasMax bool, maxValue float64) {
+	if hasMin && isFinite(minValue) {
+		v.Min = &minValue
+	}
+	if hasMax && isFinite(maxValue) {
+		v.Max = &maxValue
+	}
+}
+
+func numberValue(dp pmetric.NumberDataPoint) interface{} {
+	if dp.ValueType() == pmetric.NumberDataPointValueTypeDouble {
+		return dp.DoubleValue()
+	}
+	return dp.IntValue()
+}
+
+// newMetric returns the fields shared by every Metric API metric. Counts and
+// summaries are timestamped at the start of their interval. A count without a valid
+// interval would be rejected, so it is sent as a gauge of its value instead.
+func newMetric(name string, metricType string, start, end pcommon.Timestamp, attrs pcommon.Map) nrMetric {
+	if metricType == "count" && !hasInterval(start, end) {
+		metricType = "gauge"
+	}
+	m := nrMetric{
+		"name":       name,
+		"type":       metricType,
+		"attributes": attributesToMap(attrs),
+	}
+	if metricType != "gauge" && hasInterval(start, end) {
+		m["timestamp"] = start.AsTime().UnixMilli()
+		m["interval.ms"] = end.AsTime().Sub(start.AsTime()).Milliseconds()
+	} else {
+		m["timestamp"] = end.AsTime().UnixMilli()
+	}
+	return m
+}
+
+// metricToNRMetrics will return one Metric API metric per data point. Delta monotonic
+// sums become counts, other sums gauges, and histograms and summaries become summaries.
+// Data points with a NaN or infinite value, and summaries without an interval, are
+// dropped as the Metric API can't accept them.
+func metricToNRMetrics(currentMetric pmetric.Metric) []nrMetric {
+	var out []nrMetric
+	name := currentMetric.Name()
+	switch currentMetric.Type() {
+	case pmetric.MetricTypeGauge:
+		dps := currentMetric.Gauge().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			if !finiteNumber(dp) {
+				continue
+			}
+			m := newMetric(name, "gauge", dp.StartTimestamp(), dp.Timestamp(), dp.Attributes())
+			m["value"] = numberValue(dp)
+			out = append(out, m)
+		}
+	case pmetric.MetricTypeSum:
+		sum := currentMetric.Sum()
+		metricType := "gauge"
+		if sum.IsMonotonic() && sum.AggregationTemporality() == pmetric.AggregationTemporalityDelta {
+			metricType = "count"
+		}
+		dps := sum.DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			if !finiteNumber(dp) {
+				continue
+			}
+			m := newMetric(name, metricType, dp.StartTimestamp(), dp.Timestamp(), dp.Attributes())
+			m["value"] = numberValue(dp)
+			out = append(out, m)
+		}
+	case pmetric.MetricTypeHistogram:
+		dps := currentMetric.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			if !hasInterval(dp.StartTimestamp(), dp.Timestamp()) || !isFinite(dp.Sum()) {
+				continue
+			}
+			value := nrSummaryValue{Count: float64(dp.Count()), Sum: dp.Sum()}
+			value.setMinMax(dp.HasMin(), dp.Min(), dp.HasMax(), dp.Max())
+			m := newMetric(name, "summary", dp.StartTimestamp(), dp.Timestamp(), dp.Attributes())
+			m["value"] = value
+			out = append(out, m)
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := currentMetric.ExponentialHistogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			if !hasInterval(dp.StartTimestamp(), dp.Timestamp()) || !isFinite(dp.Sum()) {
+				continue
+			}
+			value := nrSummaryValue{Count: float64(dp.Count()), Sum: dp.Sum()}
+			value.setMinMax(dp.HasMin(), dp.Min(), dp.HasMax(), dp.Max())
+			m := newMetric(name, "summary", dp.StartTimestamp(), dp.Timestamp(), dp.Attributes())
+			m["value"] = value
+			out = append(out, m)
+		}
+	case pmetric.MetricTypeSummary:
+		dps := currentMetric.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			if !hasInterval(dp.StartTimestamp(), dp.Timestamp()) || !isFinite(dp.Sum()) {
+				continue
+			}
+			value := nrSummaryValue{Count: float64(dp.Count()), Sum: dp.Sum()}
+			// The 0 and 1 quantiles, when reported, are the min and max.
+			var minValue, maxValue float64
+			var hasMin, hasMax bool
+			for j := 0; j < dp.QuantileValues().Len(); j++ {
+				q := dp.QuantileValues().At(j)
+				switch q.Quantile() {
+				case 0:
+					hasMin, minValue = true, q.Value()
+				case 1:
+					hasMax, maxValue = true, q.Value()
+				}
+			}
+			value.setMinMax(hasMin, minValue, hasMax, maxValue)
+			m := newMetric(name, "summary", dp.StartTimestamp(), dp.Timestamp(), dp.Attributes())
+			m["value"] = value
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// MetricsToNRMetrics converts pmetric.Metrics to New Relic Metric API blocks, one per
+// resource with the resource attributes in the common block.
+func MetricsToNRMetrics(logger *zap.Logger, md pmetric.Metrics) []nrMetricBlock {
+	var blocks []nrMetricBlock
+	rms := md.ResourceMetrics()
+	logger.Debug("MetricsExporter", zap.Int("ResourceMetricsCount", rms.Len()))
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		block := nrMetricBlock{Common: nrCommon{Attributes: attributesToMap(rm.Resource().Attributes())}}
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				block.Metrics = append(block.Metrics, metricToNRMetrics(sm.Metrics().At(k))...)
+			}
+		}
+		if len(block.Metrics) > 0 {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// Build the Metric API JSON payload from pmetric.Metrics
+func BuildNRMetricPayload(logger *zap.Logger, md pmetric.Metrics) ([]byte, int, error) {
+	blocks := MetricsToNRMetrics(logger, md)
+	counter := 0
+	for _, block := range blocks {
+		counter += len(block.Metrics)
+	}
+	request, err := json.Marshal(blocks)
+	if err != nil {
+		return nil, 0, err
+	}
+	return request, counter, nil
+}
@@ -0,0 +1,160 @@
+package metrictonrmetric
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+var (
+	testStart = pcommon.NewTimestampFromTime(time.UnixMilli(1_700_000_000_000))
+	testEnd   = pcommon.NewTimestampFromTime(time.UnixMilli(1_700_000_060_000))
+)
+
+func TestMetricToNRMetrics(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(m pmetric.Metric)
+		want  []nrMetric
+	}{
+		{
+			name: "gauge",
+			build: func(m pmetric.Metric) {
+				dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+				dp.SetTimestamp(testEnd)
+				dp.SetDoubleValue(1.5)
+				dp.Attributes().PutStr("host", "a")
+			},
+			want: []nrMetric{{"name": "m", "type": "gauge", "value": 1.5, "timestamp": testEnd.AsTime().UnixMilli(),
+				"attributes": map[string]interface{}{"host": "a"}}},
+		},
+		{
+			name: "delta monotonic sum",
+			build: func(m pmetric.Metric) {
+				sum := m.SetEmptySum()
+				sum.SetIsMonotonic(true)
+				sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+				dp := sum.DataPoints().AppendEmpty()
+				dp.SetStartTimestamp(testStart)
+				dp.SetTimestamp(testEnd)
+				dp.SetIntValue(3)
+			},
+			want: []nrMetric{{"name": "m", "type": "count", "value": int64(3), "timestamp": testStart.AsTime().UnixMilli(),
+				"interval.ms": int64(60_000), "attributes": map[string]interface{}{}}},
+		},
+		{
+			name: "delta monotonic sum without start",
+			build: func(m pmetric.Metric) {
+				sum := m.SetEmptySum()
+				sum.SetIsMonotonic(true)
+				sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+				dp := sum.DataPoints().AppendEmpty()
+				dp.SetTimestamp(testEnd)
+				dp.SetIntValue(3)
+			},
+			want: []nrMetric{{"name": "m", "type": "gauge", "value": int64(3), "timestamp": testEnd.AsTime().UnixMilli(),
+				"attributes": map[string]interface{}{}}},
+		},
+		{
+			name: "cumulative sum",
+			build: func(m pmetric.Metric) {
+				sum := m.SetEmptySum()
+				sum.SetIsMonotonic(true)
+				sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+				dp := sum.DataPoints().AppendEmpty()
+				dp.SetStartTimestamp(testStart)
+				dp.SetTimestamp(testEnd)
+				dp.SetIntValue(3)
+			},
+			want: []nrMetric{{"name": "m", "type": "gauge", "value": int64(3), "timestamp": testEnd.AsTime().UnixMilli(),
+				"attributes": map[string]interface{}{}}},
+		},
+		{
+			name: "NaN gauge",
+			build: func(m pmetric.Metric) {
+				m.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(math.NaN())
+			},
+		},
+		{
+			name: "histogram",
+			build: func(m pmetric.Metric) {
+				dp := m.SetEmptyHistogram().DataPoints().AppendEmpty()
+				dp.SetStartTimestamp(testStart)
+				dp.SetTimestamp(testEnd)
+				dp.SetCount(4)
+				dp.SetSum(10)
+				dp.SetMin(1)
+				dp.SetMax(4)
+			},
+			want: []nrMetric{{"name": "m", "type": "summary", "timestamp": testStart.AsTime().UnixMilli(), "interval.ms": int64(60_000),
+				"value": nrSummaryValue{Count: 4, Sum: 10, Min: ptr(1), Max: ptr(4)}, "attributes": map[string]interface{}{}}},
+		},
+		{
+			name: "summary",
+			build: func(m pmetric.Metric) {
+				dp := m.SetEmptySummary().DataPoints().AppendEmpty()
+				dp.SetStartTimestamp(testStart)
+				dp.SetTimestamp(testEnd)
+				dp.SetCount(2)
+				dp.SetSum(3)
+				q := dp.QuantileValues().AppendEmpty()
+				q.SetQuantile(0)
+				q.SetValue(1)
+				q = dp.QuantileValues().AppendEmpty()
+				q.SetQuantile(1)
+				q.SetValue(math.NaN())
+			},
+			want: []nrMetric{{"name": "m", "type": "summary", "timestamp": testStart.AsTime().UnixMilli(), "interval.ms": int64(60_000),
+				"value": nrSummaryValue{Count: 2, Sum: 3, Min: ptr(1)}, "attributes": map[string]interface{}{}}},
+		},
+		{
+			name: "summary without interval",
+			build: func(m pmetric.Metric) {
+				dp := m.SetEmptySummary().DataPoints().AppendEmpty()
+				dp.SetTimestamp(testEnd)
+				dp.SetCount(2)
+				dp.SetSum(3)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := pmetric.NewMetric()
+			m.SetName("m")
+			tt.build(m)
+			assert.Equal(t, tt.want, metricToNRMetrics(m))
+		})
+	}
+}
+
+func TestBuildNRMetricPayloadSkipsNonFiniteValues(t *testing.T) {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("service.name", "svc")
+	dps := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetEmptyGauge().DataPoints()
+	dps.AppendEmpty().SetDoubleValue(math.NaN())
+	dps.AppendEmpty().SetDoubleValue(math.Inf(1))
+	dps.AppendEmpty().SetDoubleValue(2)
+
+	request, counter, err := BuildNRMetricPayload(zap.NewNop(), md)
+	require.NoError(t, err)
+	assert.Equal(t, 1, counter)
+
+	var blocks []nrMetricBlock
+	require.NoError(t, json.Unmarshal(request, &blocks))
+	require.Len(t, blocks, 1)
+	assert.Equal(t, map[string]interface{}{"service.name": "svc"}, blocks[0].Common.Attributes)
+	require.Len(t, blocks[0].Metrics, 1)
+	assert.Equal(t, 2.0, blocks[0].Metrics[0]["value"])
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
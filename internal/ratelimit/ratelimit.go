@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket allowing limit tokens per interval, with bursts of up
+// to limit tokens.
+type Bucket struct {
+	rate  float64 // tokens per second
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket creates a full Bucket refilling at limit tokens per interval.
+func NewBucket(limit float64, interval time.Duration) *Bucket {
+	now := time.Now
+	return &Bucket{
+		rate:   limit / interval.Seconds(),
+		burst:  limit,
+		now:    now,
+		tokens: limit,
+		last:   now(),
+	}
+}
+
+// refill adds the tokens accrued since the last call. Callers must hold mu.
+func (b *Bucket) refill() {
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// delay returns how long until n tokens are available. Requests larger than the
+// burst only wait for a full bucket, they could never be satisfied otherwise.
+// Callers must hold mu.
+func (b *Bucket) delay(n float64) time.Duration {
+	if n > b.burst {
+		n = b.burst
+	}
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+// Limiter applies a set of token buckets to each request: one token per request,
+// one per event and one per byte. Nil buckets don't limit.
+type Limiter struct {
+	requests *Bucket
+	events   *Bucket
+	bytes    *Bucket
+
+	mu sync.Mutex
+}
+
+// NewLimiter creates a Limiter from the given buckets, any of which may be nil.
+func NewLimiter(requests, events, bytes *Bucket) *Limiter {
+	return &Limiter{requests: requests, events: events, bytes: bytes}
+}
+
+func (l *Limiter) each(events, bytes int, f func(b *Bucket, n float64)) {
+	if l.requests != nil {
+		f(l.requests, 1)
+	}
+	if l.events != nil {
+		f(l.events, float64(events))
+	}
+	if l.bytes != nil {
+		f(l.bytes, float64(bytes))
+	}
+}
+
+// reserve takes the tokens for a request from every bucket, letting them go into
+// debt, and returns how long the request has to wait for the debt to be paid off.
+func (l *Limiter) reserve(events, bytes int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var wait time.Duration
+	l.each(events, bytes, func(b *Bucket, n float64) {
+		b.refill()
+		wait = max(wait, b.delay(n))
+		b.tokens -= min(n, b.burst)
+	})
+	return wait
+}
+
+// Wait blocks until the request may be sent, returning the time spent waiting.
+// It returns early with the context's error if ctx is done first.
+func (l *Limiter) Wait(ctx context.Context, events, bytes int) (time.Duration, error) {
+	wait := l.reserve(events, bytes)
+	if wait <= 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return wait, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// TryTake takes the tokens for a request if every bucket has them. Otherwise it
+// takes nothing and returns how long until the request would be allowed.
+func (l *Limiter) TryTake(events, bytes int) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var wait time.Duration
+	l.each(events, bytes, func(b *Bucket, n float64) {
+		b.refill()
+		wait = max(wait, b.delay(n))
+	})
+	if wait > 0 {
+		return false, wait
+	}
+	l.each(events, bytes, func(b *Bucket, n float64) {
+		b.tokens -= min(n, b.burst)
+	})
+	return true, 0
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBucket returns a Bucket whose clock is advanced by the returned function.
+func newTestBucket(limit float64, interval time.Duration) (*Bucket, func(time.Duration)) {
+	b := NewBucket(limit, interval)
+	now := b.last
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestTryTakeAllowsBurstThenRefills(t *testing.T) {
+	requests, advance := newTestBucket(2, time.Second)
+	l := NewLimiter(requests, nil, nil)
+
+	for i := 0; i < 2; i++ {
+		ok, _ := l.TryTake(1, 0)
+		require.True(t, ok)
+	}
+	ok, wait := l.TryTake(1, 0)
+	require.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	advance(500 * time.Millisecond)
+	ok, _ = l.TryTake(1, 0)
+	assert.True(t, ok)
+}
+
+func TestTryTakeTakesNothingWhenAnyBucketIsShort(t *testing.T) {
+	requests, _ := newTestBucket(10, time.Second)
+	bytes, _ := newTestBucket(100, time.Second)
+	l := NewLimiter(requests, nil, bytes)
+
+	ok, _ := l.TryTake(1, 80)
+	require.True(t, ok)
+	ok, _ = l.TryTake(1, 80)
+	require.False(t, ok)
+	// The rejected request didn't spend a request token.
+	assert.InDelta(t, 9, requests.tokens, 0.001)
+}
+
+func TestReserveWaitsForDebt(t *testing.T) {
+	events, _ := newTestBucket(60, time.Minute)
+	l := NewLimiter(nil, events, nil)
+
+	assert.Zero(t, l.reserve(60, 0))
+	assert.Equal(t, 30*time.Second, l.reserve(30, 0))
+}
+
+func TestRequestsLargerThanBurstOnlyWaitForFullBucket(t *testing.T) {
+	bytes, advance := newTestBucket(100, time.Second)
+	l := NewLimiter(nil, nil, bytes)
+
+	ok, _ := l.TryTake(0, 1000)
+	require.True(t, ok)
+	ok, wait := l.TryTake(0, 1000)
+	require.False(t, ok)
+	assert.Equal(t, time.Second, wait)
+
+	advance(time.Second)
+	ok, _ = l.TryTake(0, 1000)
+	assert.True(t, ok)
+}
+
+func TestWaitReturnsWhenContextIsDone(t *testing.T) {
+	l := NewLimiter(NewBucket(1, time.Hour), nil, nil)
+	_, err := l.Wait(context.Background(), 0, 0)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = l.Wait(ctx, 0, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+}
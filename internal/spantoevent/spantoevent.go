@@ -0,0 +1,141 @@
+package spantoevent
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+type nrEvent map[string]interface{}
+
+// spanToEventMap will return a single event describing the span. Resource
+// attributes (service.name and friends) are added first, then the span's own
+// attributes so an eventType attribute on the span routes it to that event type.
+func spanToEventMap(eventType string, res pcommon.Resource, span ptrace.Span) nrEvent {
+	nrEventMap := make(nrEvent)
+	nrEventMap["eventType"] = eventType
+
+	res.Attributes().Range(func(k string, val pcommon.Value) bool {
+		nrEventMap[k] = val.AsString()
+		return true
+	})
+
+	nrEventMap["type"] = "Span"
+	nrEventMap["name"] = span.Name()
+	nrEventMap["kind"] = span.Kind().String()
+	nrEventMap["trace.id"] = span.TraceID().String()
+	nrEventMap["span.id"] = span.SpanID().String()
+	if !span.ParentSpanID().IsEmpty() {
+		nrEventMap["parent.id"] = span.ParentSpanID().String()
+	}
+
+	start := span.StartTimestamp()
+	nrEventMap["duration.ms"] = DurationMillis(start, span.EndTimestamp())
+	// The Event API expects the timestamp as milliseconds since the epoch.
+	nrEventMap["timestamp"] = start.AsTime().UnixMilli()
+
+	nrEventMap["status"] = span.Status().Code().String()
+	if span.Status().Message() != "" {
+		nrEventMap["status.message"] = span.Status().Message()
+	}
+
+	span.Attributes().Range(func(k string, val pcommon.Value) bool {
+		nrEventMap[k] = val.AsString()
+		return true
+	})
+	return nrEventMap
+}
+
+// DurationMillis returns the time from start to end in milliseconds. Timestamps are
+// unsigned, so a span that ends before it starts gets a zero duration rather than
+// one that wrapped around.
+func DurationMillis(start, end pcommon.Timestamp) float64 {
+	if end < start {
+		return 0
+	}
+	return float64(end-start) / 1e6
+}
+
+// spanEventToEventMap will return an event for a single span event (exceptions,
+// retries, ...) carrying the trace.id and span.id of the span it was recorded on.
+// Those are set after the span event's attributes so they can't be overwritten.
+func spanEventToEventMap(eventType string, res pcommon.Resource, span ptrace.Span, spanEvent ptrace.SpanEvent) nrEvent {
+	nrEventMap := make(nrEvent)
+	nrEventMap["eventType"] = eventType
+
+	res.Attributes().Range(func(k string, val pcommon.Value) bool {
+		nrEventMap[k] = val.AsString()
+		return true
+	})
+
+	nrEventMap["name"] = spanEvent.Name()
+	nrEventMap["span.name"] = span.Name()
+	nrEventMap["timestamp"] = spanEvent.Timestamp().AsTime().UnixMilli()
+
+	spanEvent.Attributes().Range(func(k string, val pcommon.Value) bool {
+		nrEventMap[k] = val.AsString()
+		return true
+	})
+
+	nrEventMap["type"] = "SpanEvent"
+	nrEventMap["trace.id"] = span.TraceID().String()
+	nrEventMap["span.id"] = span.SpanID().String()
+	return nrEventMap
+}
+
+// spanLinkToEventMap will return an event for a single span link, recording both
+// the span it belongs to and the span it points at, after the link's attributes.
+func spanLinkToEventMap(eventType string, res pcommon.Resource, span ptrace.Span, link ptrace.SpanLink) nrEvent {
+	nrEventMap := make(nrEvent)
+	nrEventMap["eventType"] = eventType
+
+	res.Attributes().Range(func(k string, val pcommon.Value) bool {
+		nrEventMap[k] = val.AsString()
+		return true
+	})
+
+	nrEventMap["span.name"] = span.Name()
+	nrEventMap["timestamp"] = span.StartTimestamp().AsTime().UnixMilli()
+
+	link.Attributes().Range(func(k string, val pcommon.Value) bool {
+		nrEventMap[k] = val.AsString()
+		return true
+	})
+
+	nrEventMap["type"] = "SpanLink"
+	nrEventMap["trace.id"] = span.TraceID().String()
+	nrEventMap["span.id"] = span.SpanID().String()
+	nrEventMap["linked.trace.id"] = link.TraceID().String()
+	nrEventMap["linked.span.id"] = link.SpanID().String()
+	return nrEventMap
+}
+
+// TracesToNREvents converts ptrace.Traces to New Relic events, one per span. When
+// spanEvents or spanLinks are set each span event or span link also becomes its own event.
+func TracesToNREvents(logger *zap.Logger, td ptrace.Traces, eventType string, spanEvents bool, spanLinks bool) []nrEvent {
+	var nrEventList []nrEvent
+	rss := td.ResourceSpans()
+	logger.Debug("TracesExporter", zap.Int("ResourceSpansCount", rss.Len()))
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			for k := 0; k < ss.Spans().Len(); k++ {
+				span := ss.Spans().At(k)
+				nrEventList = append(nrEventList, spanToEventMap(eventType, rs.Resource(), span))
+				if spanEvents {
+					for l := 0; l < span.Events().Len(); l++ {
+						nrEventList = append(nrEventList, spanEventToEventMap(eventType, rs.Resource(), span, span.Events().At(l)))
+					}
+				}
+				if spanLinks {
+					for l := 0; l < span.Links().Len(); l++ {
+						nrEventList = append(nrEventList, spanLinkToEventMap(eventType, rs.Resource(), span, span.Links().At(l)))
+					}
+				}
+			}
+		}
+	}
+
+	return nrEventList
+}
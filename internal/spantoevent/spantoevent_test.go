@@ -0,0 +1,79 @@
+package spantoevent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+func TestTracesToNREventsOrder(t *testing.T) {
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for i, name := range []string{"first", "second"} {
+		span := spans.AppendEmpty()
+		span.SetName(name)
+		span.SetTraceID(pcommon.TraceID{byte(i + 1)})
+		span.SetSpanID(pcommon.SpanID{byte(i + 1)})
+		span.Events().AppendEmpty().SetName(name + " event")
+		span.Links().AppendEmpty().SetSpanID(pcommon.SpanID{9})
+	}
+	// The second span has no events, only a link.
+	spans.At(1).Events().RemoveIf(func(ptrace.SpanEvent) bool { return true })
+
+	// retryTraces maps events back to spans relying on each span being followed
+	// by its span events and then its span links.
+	events := TracesToNREvents(zap.NewNop(), td, "OtelSpan", true, true)
+	var got [][2]string
+	for _, event := range events {
+		name, _ := event["name"].(string)
+		if name == "" {
+			name = event["span.name"].(string)
+		}
+		got = append(got, [2]string{event["type"].(string), name})
+	}
+	assert.Equal(t, [][2]string{
+		{"Span", "first"},
+		{"SpanEvent", "first event"},
+		{"SpanLink", "first"},
+		{"Span", "second"},
+		{"SpanLink", "second"},
+	}, got)
+
+	assert.Len(t, TracesToNREvents(zap.NewNop(), td, "OtelSpan", false, false), 2)
+	assert.Len(t, TracesToNREvents(zap.NewNop(), td, "OtelSpan", false, true), 4)
+}
+
+func TestSpanEventAndLinkAttributesDontOverwriteParentage(t *testing.T) {
+	td := ptrace.NewTraces()
+	span := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetName("span")
+	span.SetTraceID(pcommon.TraceID{1})
+	span.SetSpanID(pcommon.SpanID{2})
+	spanEvent := span.Events().AppendEmpty()
+	spanEvent.SetName("exception")
+	link := span.Links().AppendEmpty()
+	link.SetTraceID(pcommon.TraceID{3})
+	link.SetSpanID(pcommon.SpanID{4})
+	for _, attrs := range []pcommon.Map{spanEvent.Attributes(), link.Attributes()} {
+		attrs.PutStr("trace.id", "bogus")
+		attrs.PutStr("span.id", "bogus")
+		attrs.PutStr("type", "bogus")
+		attrs.PutStr("linked.span.id", "bogus")
+		attrs.PutStr("exception.type", "IOError")
+	}
+
+	events := TracesToNREvents(zap.NewNop(), td, "OtelSpan", true, true)
+	require.Len(t, events, 3)
+	for _, event := range events[1:] {
+		assert.Equal(t, span.TraceID().String(), event["trace.id"])
+		assert.Equal(t, span.SpanID().String(), event["span.id"])
+		assert.Equal(t, "IOError", event["exception.type"])
+	}
+	assert.Equal(t, "SpanEvent", events[1]["type"])
+	assert.Equal(t, "SpanLink", events[2]["type"])
+	assert.Equal(t, link.SpanID().String(), events[2]["linked.span.id"])
+}
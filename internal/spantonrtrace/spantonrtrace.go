@@ -0,0 +1,99 @@
+package spantonrtrace
+
+import (
+	"encoding/json"
+
+	"github.com/jwang25/nreventexporter/internal/spantoevent"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// nrTraceBlock is one element of a New Relic Trace API payload in the newrelic
+// data format. Attributes shared by every span in the block go in common, see
+// https://docs.newrelic.com/docs/distributed-tracing/trace-api/report-new-relic-format-traces-trace-api/
+type nrTraceBlock struct {
+	Common nrCommon `json:"common"`
+	Spans  []nrSpan `json:"spans"`
+}
+
+type nrCommon struct {
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type nrSpan struct {
+	ID         string                 `json:"id"`
+	TraceID    string                 `json:"trace.id"`
+	Timestamp  int64                  `json:"timestamp"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// spanToNRSpan will return a Trace API span. Name, duration, parent and status
+// are reserved attributes in the newrelic format.
+func spanToNRSpan(span ptrace.Span) nrSpan {
+	attrs := make(map[string]interface{})
+	span.Attributes().Range(func(k string, val pcommon.Value) bool {
+		attrs[k] = val.AsString()
+		return true
+	})
+	attrs["name"] = span.Name()
+	attrs["span.kind"] = span.Kind().String()
+	start := span.StartTimestamp()
+	attrs["duration.ms"] = spantoevent.DurationMillis(start, span.EndTimestamp())
+	if !span.ParentSpanID().IsEmpty() {
+		attrs["parent.id"] = span.ParentSpanID().String()
+	}
+	if span.Status().Code() != ptrace.StatusCodeUnset {
+		attrs["otel.status_code"] = span.Status().Code().String()
+	}
+	if span.Status().Message() != "" {
+		attrs["otel.status_description"] = span.Status().Message()
+	}
+	if span.Status().Code() == ptrace.StatusCodeError {
+		attrs["error"] = true
+	}
+
+	return nrSpan{
+		ID:         span.SpanID().String(),
+		TraceID:    span.TraceID().String(),
+		Timestamp:  start.AsTime().UnixMilli(),
+		Attributes: attrs,
+	}
+}
+
+// TracesToNRTraces converts ptrace.Traces to New Relic Trace API blocks, one per
+// resource with the resource (service) attributes in the common block.
+func TracesToNRTraces(logger *zap.Logger, td ptrace.Traces) []nrTraceBlock {
+	var blocks []nrTraceBlock
+	rss := td.ResourceSpans()
+	logger.Debug("TracesExporter", zap.Int("ResourceSpansCount", rss.Len()))
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		block := nrTraceBlock{Common: nrCommon{Attributes: make(map[string]interface{})}}
+		rs.Resource().Attributes().Range(func(k string, val pcommon.Value) bool {
+			block.Common.Attributes[k] = val.AsString()
+			return true
+		})
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			for k := 0; k < ss.Spans().Len(); k++ {
+				block.Spans = append(block.Spans, spanToNRSpan(ss.Spans().At(k)))
+			}
+		}
+		if len(block.Spans) > 0 {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// Build the Trace API JSON payload from ptrace.Traces
+func BuildNRTracePayload(logger *zap.Logger, td ptrace.Traces) ([]byte, int) {
+	blocks := TracesToNRTraces(logger, td)
+	counter := 0
+	for _, block := range blocks {
+		counter += len(block.Spans)
+	}
+	request, _ := json.Marshal(blocks)
+	return request, counter
+}
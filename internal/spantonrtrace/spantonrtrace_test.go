@@ -0,0 +1,69 @@
+package spantonrtrace
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+func TestBuildNRTracePayload(t *testing.T) {
+	start := time.UnixMilli(1_700_000_000_000)
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "checkout")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+
+	span := spans.AppendEmpty()
+	span.SetName("POST /pay")
+	span.SetKind(ptrace.SpanKindServer)
+	span.SetTraceID(pcommon.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	span.SetSpanID(pcommon.SpanID{1, 2, 3, 4, 5, 6, 7, 8})
+	span.SetParentSpanID(pcommon.SpanID{8, 7, 6, 5, 4, 3, 2, 1})
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(start.Add(250 * time.Millisecond)))
+	span.Status().SetCode(ptrace.StatusCodeError)
+	span.Status().SetMessage("card declined")
+	span.Attributes().PutStr("http.method", "POST")
+
+	// A span that ends before it starts gets a zero duration.
+	span = spans.AppendEmpty()
+	span.SetName("clock skew")
+	span.SetTraceID(pcommon.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	span.SetSpanID(pcommon.SpanID{2, 2, 2, 2, 2, 2, 2, 2})
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(start.Add(-time.Second)))
+
+	request, counter := BuildNRTracePayload(zap.NewNop(), td)
+	assert.Equal(t, 2, counter)
+	require.JSONEq(t, `[{
+		"common": {"attributes": {"service.name": "checkout"}},
+		"spans": [
+			{
+				"id": "0102030405060708",
+				"trace.id": "0102030405060708090a0b0c0d0e0f10",
+				"timestamp": 1700000000000,
+				"attributes": {
+					"name": "POST /pay",
+					"span.kind": "Server",
+					"duration.ms": 250,
+					"parent.id": "0807060504030201",
+					"otel.status_code": "Error",
+					"otel.status_description": "card declined",
+					"error": true,
+					"http.method": "POST"
+				}
+			},
+			{
+				"id": "0202020202020202",
+				"trace.id": "0102030405060708090a0b0c0d0e0f10",
+				"timestamp": 1700000000000,
+				"attributes": {"name": "clock skew", "span.kind": "Unspecified", "duration.ms": 0}
+			}
+		]
+	}]`, string(request))
+}
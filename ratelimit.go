@@ -0,0 +1,67 @@
+package nreventexporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jwang25/nreventexporter/internal/ratelimit"
+	"go.opentelemetry.io/collector/component"
+)
+
+// sharedLimiters holds the rate limiter of each exporter component, so the metrics,
+// logs and traces pipelines using the same exporter share its limits.
+var sharedLimiters = &limiterRegistry{entries: make(map[component.ID]*limiterEntry)}
+
+type limiterEntry struct {
+	limiter *ratelimit.Limiter
+	refs    int
+}
+
+type limiterRegistry struct {
+	mu      sync.Mutex
+	entries map[component.ID]*limiterEntry
+}
+
+// acquire returns the limiter for id, creating it from cfg for the first signal.
+// It returns nil when cfg sets no limits.
+func (r *limiterRegistry) acquire(id component.ID, cfg RateLimitConfig) *ratelimit.Limiter {
+	if cfg.RequestsPerSecond <= 0 && cfg.EventsPerMinute <= 0 && cfg.BytesPerMinute <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.entries[id]
+	if !ok {
+		entry = &limiterEntry{limiter: ratelimit.NewLimiter(
+			newBucket(cfg.RequestsPerSecond, time.Second),
+			newBucket(float64(cfg.EventsPerMinute), time.Minute),
+			newBucket(float64(cfg.BytesPerMinute), time.Minute),
+		)}
+		r.entries[id] = entry
+	}
+	entry.refs++
+	return entry.limiter
+}
+
+// release drops a reference to the limiter for id, forgetting it once no signal
+// uses it so a reloaded config starts with fresh limits.
+func (r *limiterRegistry) release(id component.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.entries[id]
+	if !ok {
+		return
+	}
+	entry.refs--
+	if entry.refs <= 0 {
+		delete(r.entries, id)
+	}
+}
+
+// newBucket returns a token bucket for limit per interval, or nil if limit is unset.
+func newBucket(limit float64, interval time.Duration) *ratelimit.Bucket {
+	if limit <= 0 {
+		return nil
+	}
+	return ratelimit.NewBucket(limit, interval)
+}
@@ -0,0 +1,56 @@
+package nreventexporter
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+)
+
+func TestSharedLimitersArePerComponent(t *testing.T) {
+	cfg := RateLimitConfig{RequestsPerSecond: 1}
+	a := component.MustNewIDWithName("nreventexporter", "a")
+	b := component.MustNewIDWithName("nreventexporter", "b")
+
+	metrics := sharedLimiters.acquire(a, cfg)
+	logs := sharedLimiters.acquire(a, cfg)
+	other := sharedLimiters.acquire(b, cfg)
+	assert.Same(t, metrics, logs)
+	assert.NotSame(t, metrics, other)
+
+	sharedLimiters.release(a)
+	assert.Same(t, metrics, sharedLimiters.acquire(a, cfg))
+	sharedLimiters.release(a)
+	sharedLimiters.release(a)
+	assert.NotSame(t, metrics, sharedLimiters.acquire(a, cfg), "a limiter nobody uses is forgotten")
+	sharedLimiters.release(a)
+	sharedLimiters.release(b)
+	assert.Empty(t, sharedLimiters.entries)
+}
+
+func TestSharedLimiterWithoutLimits(t *testing.T) {
+	assert.Nil(t, sharedLimiters.acquire(component.MustNewID("nreventexporter"), RateLimitConfig{}))
+	assert.Empty(t, sharedLimiters.entries)
+}
+
+func TestOpenBreakerDoesNotSpendRateLimitTokens(t *testing.T) {
+	e := newTestExporter(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, func(cfg *Config) {
+		cfg.CircuitBreaker = CircuitBreakerConfig{Enabled: true, FailureThreshold: 1, CoolDown: time.Hour}
+		cfg.RateLimit = RateLimitConfig{RequestsPerSecond: 2, Mode: rateLimitThrottle}
+	})
+	url := e.config.Endpoint
+
+	require.Error(t, e.export(context.Background(), url, []byte("[]"), e.nrAPIResponseHandler, 0, nil))
+	for i := 0; i < 3; i++ {
+		err := e.export(context.Background(), url, []byte("[]"), e.nrAPIResponseHandler, 0, nil)
+		require.ErrorContains(t, err, "circuit breaker open")
+	}
+	ok, _ := e.limiter.TryTake(0, 0)
+	assert.True(t, ok, "only the request that was sent took a token")
+}
@@ -0,0 +1,204 @@
+package nreventexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+// defaultRoutingAttribute is the resource attribute used to pick an account when
+// routing_attribute is not set.
+const defaultRoutingAttribute = "nr.account_id"
+
+// AccountConfig describes a New Relic account data can be routed to.
+type AccountConfig struct {
+	// AccountID is used with the region to build the Event API URL when Endpoint is not set.
+	AccountID string `mapstructure:"account_id"`
+	// APIKey is the key requests for this account are sent with.
+	APIKey configopaque.String `mapstructure:"api_key"`
+	// Endpoint overrides the URL data for this account is sent to.
+	Endpoint string `mapstructure:"endpoint"`
+	// Region is the account's New Relic datacenter. It defaults to the region of
+	// APIKey: EU for eu01 license keys, else the exporter's region.
+	Region string `mapstructure:"region"`
+}
+
+type apiKeyContextKey struct{}
+
+// contextWithAPIKey returns a context whose requests are sent with apiKey instead
+// of the configured api_key.
+func contextWithAPIKey(ctx context.Context, apiKey configopaque.String) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, apiKey)
+}
+
+// apiKeyFromContext returns the API key set by contextWithAPIKey, if any.
+func apiKeyFromContext(ctx context.Context) (configopaque.String, bool) {
+	apiKey, ok := ctx.Value(apiKeyContextKey{}).(configopaque.String)
+	return apiKey, ok && apiKey != ""
+}
+
+// firstMetadataValue returns the first value of key in the client metadata on ctx.
+func firstMetadataValue(ctx context.Context, key string) string {
+	if key == "" {
+		return ""
+	}
+	if values := client.FromContext(ctx).Metadata.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// withClientCredentials applies the API key and account ID a tenant sent in its
+// request metadata. It returns the context to send with, carrying the tenant's key,
+// and the endpoint for the tenant's account in the region of the key in use, or "" to
+// use the configured destination. Without metadata the configured api_key and
+// endpoint are used.
+func (e *baseExporter) withClientCredentials(ctx context.Context) (context.Context, string) {
+	if apiKey := firstMetadataValue(ctx, e.config.APIKeyMetadataKey); apiKey != "" {
+		ctx = contextWithAPIKey(ctx, configopaque.String(apiKey))
+	}
+	accountID := firstMetadataValue(ctx, e.config.AccountIDMetadataKey)
+	if accountID == "" || e.config.MetricsFormat == formatMetric {
+		return ctx, ""
+	}
+	if !isNumeric(accountID) {
+		e.logger.Warn("Ignoring non numeric account ID from client metadata", zap.String("metadata_key", e.config.AccountIDMetadataKey))
+		return ctx, ""
+	}
+	return ctx, eventAPIEndpoint(e.region(ctx), accountID)
+}
+
+func (cfg *Config) routingAttribute() string {
+	if cfg.RoutingAttribute != "" {
+		return cfg.RoutingAttribute
+	}
+	return defaultRoutingAttribute
+}
+
+// validateAccounts checks that every account can be sent to and that the default
+// account, if set, is one of them.
+func (cfg *Config) validateAccounts() error {
+	for name, account := range cfg.Accounts {
+		if account.APIKey == "" {
+			return fmt.Errorf("accounts::%s: api_key must be set", name)
+		}
+		if err := validateKeyFormat(cfg.resolvedKeyType(), account.APIKey); err != nil {
+			return fmt.Errorf("accounts::%s: api_key %w", name, err)
+		}
+		if account.AccountID != "" && !isNumeric(account.AccountID) {
+			return fmt.Errorf("accounts::%s: account_id must be numeric, got %q", name, account.AccountID)
+		}
+		if account.Region != "" {
+			region, ok := lookupRegion(account.Region)
+			if !ok {
+				return fmt.Errorf("accounts::%s: region must be one of %q, %q, %q or %q, got %q",
+					name, regionUS, regionEU, regionFedRAMP, regionStaging, account.Region)
+			}
+			if region != regionEU && cfg.isEULicenseKey(account.APIKey) {
+				return fmt.Errorf("accounts::%s: region %q contradicts the EU license key", name, region)
+			}
+		}
+		if account.Endpoint != "" {
+			u, err := url.Parse(account.Endpoint)
+			if err != nil {
+				return fmt.Errorf("accounts::%s: endpoint must be a valid URL: %w", name, err)
+			}
+			known := account.Region != "" || cfg.hasKnownRegion(account.APIKey)
+			if other, ok := regionOfHost(u.Hostname()); ok && known && other != cfg.accountRegion(account) {
+				return fmt.Errorf("accounts::%s: endpoint %q belongs to region %s but the account's region is %s",
+					name, account.Endpoint, other, cfg.accountRegion(account))
+			}
+		}
+	}
+	if cfg.DefaultAccount != "" {
+		if _, ok := cfg.Accounts[cfg.DefaultAccount]; !ok {
+			return fmt.Errorf("default_account %q is not one of the configured accounts", cfg.DefaultAccount)
+		}
+	}
+	return nil
+}
+
+// accountEndpoint returns the URL data for the account is sent to, in the account's
+// region, or "" to use the exporter's configured destination.
+func (e *baseExporter) accountEndpoint(account AccountConfig) string {
+	if account.Endpoint != "" {
+		return account.Endpoint
+	}
+	region := e.config.accountRegion(account)
+	if e.config.MetricsFormat == formatMetric {
+		if e.config.MetricsEndpoint != "" {
+			return ""
+		}
+		return metricAPIEndpoint(region)
+	}
+	if account.AccountID != "" {
+		return eventAPIEndpoint(region, account.AccountID)
+	}
+	return ""
+}
+
+// partitionMetrics splits md by the account its resources are routed to. Resources
+// without a matching account go to the default account, or to the exporter's own
+// api_key and endpoint (the "" partition) when there is no default account.
+func (e *baseExporter) partitionMetrics(md pmetric.Metrics) map[string]pmetric.Metrics {
+	attribute := e.config.routingAttribute()
+	partitions := make(map[string]pmetric.Metrics)
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		name := e.config.DefaultAccount
+		if val, ok := rm.Resource().Attributes().Get(attribute); ok {
+			if _, known := e.config.Accounts[val.AsString()]; known {
+				name = val.AsString()
+			}
+		}
+		partition, ok := partitions[name]
+		if !ok {
+			partition = pmetric.NewMetrics()
+			partitions[name] = partition
+		}
+		rm.CopyTo(partition.ResourceMetrics().AppendEmpty())
+	}
+	return partitions
+}
+
+// pushMetricsByAccount sends one request per account, each with that account's key.
+// Only the metrics of accounts whose request can be retried are handed back for retry,
+// so accounts that were delivered to are not sent duplicates.
+func (e *baseExporter) pushMetricsByAccount(ctx context.Context, md pmetric.Metrics) error {
+	var retryable, permanent []error
+	retryData := pmetric.NewMetrics()
+	for name, partition := range e.partitionMetrics(md) {
+		accountCtx, endpoint := ctx, ""
+		if account, ok := e.config.Accounts[name]; ok {
+			accountCtx = contextWithAPIKey(ctx, account.APIKey)
+			endpoint = e.accountEndpoint(account)
+		}
+		err := e.sendMetrics(accountCtx, partition, endpoint)
+		switch {
+		case err == nil:
+		case consumererror.IsPermanent(err):
+			e.logger.Error("Dropping metrics for account", zap.String("account", name), zap.Error(err))
+			permanent = append(permanent, fmt.Errorf("account %q: %w", name, err))
+		default:
+			retryable = append(retryable, fmt.Errorf("account %q: %w", name, err))
+			// Only the part of the partition that wasn't delivered is retried.
+			var partial consumererror.Metrics
+			if errors.As(err, &partial) {
+				partition = partial.Data()
+			}
+			partition.ResourceMetrics().MoveAndAppendTo(retryData.ResourceMetrics())
+		}
+	}
+	if len(retryable) > 0 {
+		return consumererror.NewMetrics(errors.Join(retryable...), retryData)
+	}
+	return errors.Join(permanent...)
+}
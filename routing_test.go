@@ -0,0 +1,44 @@
+package nreventexporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/config/configopaque"
+)
+
+func TestAccountEndpointUsesAccountRegion(t *testing.T) {
+	euKey := configopaque.String("eu01" + strings.Repeat("x", licenseKeyLength-4))
+	usKey := configopaque.String(strings.Repeat("x", licenseKeyLength))
+	e := &baseExporter{config: &Config{KeyType: keyTypeLicense}}
+
+	assert.Equal(t, "https://insights-collector.eu01.nr-data.net/v1/accounts/2/events",
+		e.accountEndpoint(AccountConfig{AccountID: "2", APIKey: euKey}), "the EU key decides the region")
+	assert.Equal(t, "https://insights-collector.newrelic.com/v1/accounts/2/events",
+		e.accountEndpoint(AccountConfig{AccountID: "2", APIKey: usKey}))
+	assert.Equal(t, "https://gov-insights-collector.newrelic.com/v1/accounts/2/events",
+		e.accountEndpoint(AccountConfig{AccountID: "2", APIKey: usKey, Region: "fedramp"}))
+
+	e.config.MetricsFormat = formatMetric
+	assert.Equal(t, "https://metric-api.eu.newrelic.com/metric/v1",
+		e.accountEndpoint(AccountConfig{APIKey: euKey}))
+}
+
+func TestClientCredentialsUseRegionOfClientKey(t *testing.T) {
+	e := &baseExporter{config: &Config{
+		KeyType:              keyTypeLicense,
+		APIKey:               configopaque.String(strings.Repeat("x", licenseKeyLength)),
+		APIKeyMetadataKey:    "x-nr-key",
+		AccountIDMetadataKey: "x-nr-account",
+	}}
+	ctx := client.NewContext(context.Background(), client.Info{Metadata: client.NewMetadata(map[string][]string{
+		"x-nr-key":     {"eu01" + strings.Repeat("x", licenseKeyLength-4)},
+		"x-nr-account": {"2"},
+	})})
+
+	_, endpoint := e.withClientCredentials(ctx)
+	assert.Equal(t, "https://insights-collector.eu01.nr-data.net/v1/accounts/2/events", endpoint)
+}
@@ -0,0 +1,256 @@
+package nreventexporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// errPayloadTooLarge is wrapped into the error returned by export when the
+// endpoint rejects a request with 413 Payload Too Large.
+var errPayloadTooLarge = errors.New("payload too large")
+
+// exportEvents sends events to the Event API. When the payload is rejected as too
+// large the events are split in half and each half is sent on its own, recursively,
+// so only events that are too large to be sent even on their own are dropped. Along
+// with the error it returns the indexes of the events that can be retried, so halves
+// that were delivered are not sent again.
+func exportEvents[T any](ctx context.Context, e *baseExporter, url string, events []T) ([]int, error) {
+	request, err := json.Marshal(events)
+	if err != nil {
+		return nil, consumererror.NewPermanent(err)
+	}
+
+	e.logger.Debug("Exporting events", zap.Int("events", len(events)), zap.Int("size", len(request)))
+
+	err = e.export(ctx, url, request, e.nrAPIResponseHandler, len(events), nil)
+	if !errors.Is(err, errPayloadTooLarge) {
+		return retryIndexes(err, len(events)), err
+	}
+	if len(events) <= 1 {
+		// err is already permanent, the event can't be made any smaller.
+		return nil, fmt.Errorf("dropping event of %d bytes: %w", len(request), err)
+	}
+
+	e.logger.Debug("Payload too large, splitting events", zap.Int("events", len(events)))
+	mid := len(events) / 2
+	retryFirst, errFirst := exportEvents(ctx, e, url, events[:mid])
+	retrySecond, errSecond := exportEvents(ctx, e, url, events[mid:])
+	for _, i := range retrySecond {
+		retryFirst = append(retryFirst, mid+i)
+	}
+	return retryFirst, joinSplitErrors(e.logger, errFirst, errSecond)
+}
+
+// joinSplitErrors combines the results of sending both halves of a split payload.
+// If either half can be retried the combined error is retryable, so events that
+// could still be delivered are not dropped along with permanently rejected ones.
+// The permanent errors are left out of it and logged instead, as the events they
+// rejected are dropped here.
+func joinSplitErrors(logger *zap.Logger, errs ...error) error {
+	var retryable, permanent []error
+	for _, err := range errs {
+		switch {
+		case err == nil:
+		case consumererror.IsPermanent(err):
+			permanent = append(permanent, err)
+		default:
+			retryable = append(retryable, err)
+		}
+	}
+	if len(retryable) == 0 {
+		return errors.Join(permanent...)
+	}
+	for _, err := range permanent {
+		logger.Error("Dropping events rejected by the API", zap.Error(err))
+	}
+	return errors.Join(retryable...)
+}
+
+// defaultMaxConcurrentChunks is used when max_concurrent_chunks is not set.
+const defaultMaxConcurrentChunks = 4
+
+// exportEventsInChunks sends events in requests of at most chunk_size events, with
+// up to max_concurrent_chunks requests in flight, so a large batch over a slow link
+// doesn't hold up the queue consumer behind one long serial POST. Without a chunk
+// size all events are sent in one request. Along with the error it returns the
+// indexes of the events in chunks that can be retried, so chunks that were
+// delivered are not sent again.
+func exportEventsInChunks[T any](ctx context.Context, e *baseExporter, url string, events []T) ([]int, error) {
+	size := e.config.ChunkSize
+	if size <= 0 || len(events) <= size {
+		return exportEvents(ctx, e, url, events)
+	}
+
+	maxInFlight := e.config.MaxConcurrentChunks
+	if maxInFlight <= 0 {
+		maxInFlight = defaultMaxConcurrentChunks
+	}
+
+	numChunks := (len(events) + size - 1) / size
+	errs := make([]error, numChunks)
+	retries := make([][]int, numChunks)
+	sem := make(chan struct{}, maxInFlight)
+	var wg sync.WaitGroup
+	for i := 0; i < numChunks; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			retries[i] = retryIndexes(errs[i], min(size, len(events)-i*size))
+			continue
+		}
+		chunk := events[i*size : min((i+1)*size, len(events))]
+		wg.Add(1)
+		go func(i int, chunk []T) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			retries[i], errs[i] = exportEvents(ctx, e, url, chunk)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	var retry []int
+	for i, chunkRetry := range retries {
+		for _, j := range chunkRetry {
+			retry = append(retry, i*size+j)
+		}
+	}
+	return retry, joinChunkErrors(e.logger, errs)
+}
+
+// retryIndexes returns the indexes of all n events if err can be retried.
+func retryIndexes(err error, n int) []int {
+	if err == nil || consumererror.IsPermanent(err) {
+		return nil
+	}
+	indexes := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// joinChunkErrors aggregates the results of sending each chunk into one error that
+// names the failed chunks and which of them can be retried.
+func joinChunkErrors(logger *zap.Logger, errs []error) error {
+	var failed, retryable []int
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		failed = append(failed, i+1)
+		if !consumererror.IsPermanent(err) {
+			retryable = append(retryable, i+1)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d chunks failed (chunks %v, retryable %v): %w",
+		len(failed), len(errs), failed, retryable, joinSplitErrors(logger, errs...))
+}
+
+// indexSet turns a list of event indexes into a set.
+func indexSet(indexes []int) map[int]bool {
+	set := make(map[int]bool, len(indexes))
+	for _, i := range indexes {
+		set[i] = true
+	}
+	return set
+}
+
+// retryMetrics narrows a retryable err to the metrics whose events are listed in
+// retry, so metrics that were delivered are not sent again. Each metric is converted
+// to one event, in order.
+func retryMetrics(err error, md pmetric.Metrics, retry []int) error {
+	if err == nil || consumererror.IsPermanent(err) || len(retry) == 0 {
+		return err
+	}
+	keep := indexSet(retry)
+	n := 0
+	data := pmetric.NewMetrics()
+	md.CopyTo(data)
+	data.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			sm.Metrics().RemoveIf(func(pmetric.Metric) bool {
+				drop := !keep[n]
+				n++
+				return drop
+			})
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+	return consumererror.NewMetrics(err, data)
+}
+
+// retryLogs narrows a retryable err to the log records whose events are listed in
+// retry. Each log record is converted to one event, in order.
+func retryLogs(err error, ld plog.Logs, retry []int) error {
+	if err == nil || consumererror.IsPermanent(err) || len(retry) == 0 {
+		return err
+	}
+	keep := indexSet(retry)
+	n := 0
+	data := plog.NewLogs()
+	ld.CopyTo(data)
+	data.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(plog.LogRecord) bool {
+				drop := !keep[n]
+				n++
+				return drop
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	return consumererror.NewLogs(err, data)
+}
+
+// retryTraces narrows a retryable err to the spans with an event listed in retry.
+// Each span is converted to one event followed, when enabled, by one per span event
+// and one per span link.
+func retryTraces(err error, td ptrace.Traces, retry []int, spanEvents, spanLinks bool) error {
+	if err == nil || consumererror.IsPermanent(err) || len(retry) == 0 {
+		return err
+	}
+	keep := indexSet(retry)
+	n := 0
+	data := ptrace.NewTraces()
+	td.CopyTo(data)
+	data.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			ss.Spans().RemoveIf(func(span ptrace.Span) bool {
+				count := 1
+				if spanEvents {
+					count += span.Events().Len()
+				}
+				if spanLinks {
+					count += span.Links().Len()
+				}
+				drop := true
+				for i := n; i < n+count; i++ {
+					if keep[i] {
+						drop = false
+						break
+					}
+				}
+				n += count
+				return drop
+			})
+			return ss.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+	return consumererror.NewTraces(err, data)
+}
@@ -0,0 +1,215 @@
+package nreventexporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jwang25/nreventexporter/internal/metadata"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configcompression"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newTestExporter starts an exporter sending uncompressed requests to a test server
+// running handler.
+func newTestExporter(t *testing.T, handler http.HandlerFunc, configure func(*Config)) *baseExporter {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = srv.URL + "/v1/accounts/1/events"
+	cfg.APIKey = "NRAK-test"
+	cfg.KeyType = keyTypeUser
+	cfg.Compression = configcompression.Type("none")
+	if configure != nil {
+		configure(cfg)
+	}
+	require.NoError(t, cfg.Validate())
+
+	set := exportertest.NewNopSettings()
+	tb, err := metadata.NewTelemetryBuilder(set.TelemetrySettings)
+	require.NoError(t, err)
+	e, err := newExporter(pipeline.SignalLogs, *cfg, set, tb)
+	require.NoError(t, err)
+	require.NoError(t, e.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() { require.NoError(t, e.Shutdown(context.Background())) })
+	return e
+}
+
+// testLogs returns n log records with the messages "0" to "n-1".
+func testLogs(n int) plog.Logs {
+	ld := plog.NewLogs()
+	records := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	for i := 0; i < n; i++ {
+		records.AppendEmpty().Body().SetStr(fmt.Sprint(i))
+	}
+	return ld
+}
+
+// requestMessages decodes the messages of the events in an Event API request.
+func requestMessages(t *testing.T, r *http.Request) []string {
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	var events []map[string]any
+	require.NoError(t, json.Unmarshal(body, &events))
+	messages := make([]string, 0, len(events))
+	for _, event := range events {
+		messages = append(messages, event["message"].(string))
+	}
+	return messages
+}
+
+// logMessages returns the bodies of the log records in ld.
+func logMessages(ld plog.Logs) []string {
+	var messages []string
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		sls := rls.At(i).ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			lrs := sls.At(j).LogRecords()
+			for k := 0; k < lrs.Len(); k++ {
+				messages = append(messages, lrs.At(k).Body().AsString())
+			}
+		}
+	}
+	return messages
+}
+
+func TestExportEventsInChunksRetriesOnlyFailedChunks(t *testing.T) {
+	var mu sync.Mutex
+	var delivered []string
+	e := newTestExporter(t, func(w http.ResponseWriter, r *http.Request) {
+		messages := requestMessages(t, r)
+		if strings.Join(messages, ",") == "4,5" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		mu.Lock()
+		delivered = append(delivered, messages...)
+		mu.Unlock()
+	}, func(cfg *Config) {
+		cfg.ChunkSize = 2
+	})
+
+	err := e.pushLogs(context.Background(), testLogs(10))
+	require.Error(t, err)
+	require.False(t, consumererror.IsPermanent(err))
+	var partial consumererror.Logs
+	require.True(t, errors.As(err, &partial))
+	require.Equal(t, []string{"4", "5"}, logMessages(partial.Data()))
+	require.Len(t, delivered, 8)
+}
+
+func TestExportEventsInChunksDropsPermanentFailures(t *testing.T) {
+	e := newTestExporter(t, func(w http.ResponseWriter, r *http.Request) {
+		if requestMessages(t, r)[0] == "0" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}, func(cfg *Config) {
+		cfg.ChunkSize = 2
+	})
+
+	err := e.pushLogs(context.Background(), testLogs(4))
+	require.Error(t, err)
+	require.True(t, consumererror.IsPermanent(err))
+}
+
+func TestExportEventsRetriesOnlyUndeliveredHalfAfterSplit(t *testing.T) {
+	var delivered []string
+	e := newTestExporter(t, func(w http.ResponseWriter, r *http.Request) {
+		messages := requestMessages(t, r)
+		switch {
+		case len(messages) > 2:
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		case messages[0] == "0":
+			// Too large even on its own.
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		case messages[0] == "2":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			delivered = append(delivered, messages...)
+		}
+	}, nil)
+
+	err := e.pushLogs(context.Background(), testLogs(8))
+	require.Error(t, err)
+	require.False(t, consumererror.IsPermanent(err))
+	var partial consumererror.Logs
+	require.True(t, errors.As(err, &partial))
+	require.Equal(t, []string{"2", "3"}, logMessages(partial.Data()))
+	require.Equal(t, []string{"1", "4", "5", "6", "7"}, delivered)
+}
+
+func TestExportEventsDropsEventTooLargeToSend(t *testing.T) {
+	e := newTestExporter(t, func(w http.ResponseWriter, r *http.Request) {
+		if requestMessages(t, r)[0] == "0" {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}, nil)
+
+	err := e.pushLogs(context.Background(), testLogs(1))
+	require.ErrorIs(t, err, errPayloadTooLarge)
+	require.True(t, consumererror.IsPermanent(err))
+}
+
+func TestExportEventsLogsPermanentFailureNextToRetryableOne(t *testing.T) {
+	e := newTestExporter(t, func(w http.ResponseWriter, r *http.Request) {
+		messages := requestMessages(t, r)
+		switch {
+		case len(messages) > 2:
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		case messages[0] == "0":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}, nil)
+	core, logs := observer.New(zap.ErrorLevel)
+	e.logger = zap.New(core)
+
+	err := e.pushLogs(context.Background(), testLogs(4))
+	require.Error(t, err)
+	require.False(t, consumererror.IsPermanent(err))
+	var partial consumererror.Logs
+	require.True(t, errors.As(err, &partial))
+	require.Equal(t, []string{"2", "3"}, logMessages(partial.Data()))
+
+	dropped := logs.FilterMessage("Dropping events rejected by the API").All()
+	require.Len(t, dropped, 1)
+	require.Contains(t, dropped[0].ContextMap()["error"], "400")
+}
+
+func TestExportEventsInChunksLogsPermanentFailureNextToRetryableOne(t *testing.T) {
+	e := newTestExporter(t, func(w http.ResponseWriter, r *http.Request) {
+		if requestMessages(t, r)[0] == "0" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, func(cfg *Config) {
+		cfg.ChunkSize = 2
+	})
+	core, logs := observer.New(zap.ErrorLevel)
+	e.logger = zap.New(core)
+
+	err := e.pushLogs(context.Background(), testLogs(4))
+	require.Error(t, err)
+	require.False(t, consumererror.IsPermanent(err))
+	require.Contains(t, err.Error(), "chunks [1 2], retryable [2]")
+	require.Equal(t, 1, logs.FilterMessage("Dropping events rejected by the API").Len())
+}